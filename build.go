@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/config"
+	"github.com/icholy/whisperd/internal/daemon"
+	"github.com/icholy/whisperd/internal/dsp"
+	"github.com/icholy/whisperd/internal/openai"
+	"github.com/icholy/whisperd/internal/output"
+	"github.com/icholy/whisperd/internal/postproc"
+	"github.com/icholy/whisperd/internal/vad"
+	"github.com/icholy/whisperd/internal/voicecmd"
+)
+
+// build creates the daemon configuration described by cfg.
+// The keyboard is the uinput device used by the typing outputs, and spacing
+// remembers the previous dictation across reloads.
+func build(cfg *config.Config, keyboard *os.File, spacing *postproc.Spacing, log *slog.Logger) (daemon.Config, error) {
+	client := &openai.Client{APIKey: cfg.OpenAI.Key, BaseURL: cfg.OpenAI.BaseURL}
+	dcfg := daemon.Config{
+		Inputs:           cfg.Inputs,
+		Client:           *client,
+		RepeatKeyCode:    uint16(cfg.RepeatKey),
+		Device:           cfg.Audio.Device,
+		Dump:             cfg.Audio.Dump,
+		Format:           cfg.Audio.Format,
+		ErrorTimeout:     cfg.ErrorTimeout,
+		SilenceWarning:   cfg.Audio.SilenceWarning,
+		EndSilence:       cfg.HandsFree.EndSilence,
+		HandsFreeTimeout: cfg.HandsFree.Timeout,
+		MaxDuration:      cfg.Audio.MaxDuration,
+		ChunkDuration:    cfg.Audio.ChunkDuration,
+		ChunkParallel:    cfg.Audio.ChunkParallel,
+	}
+	if cfg.Audio.File != "" {
+		dcfg.Audio = audio.File{Path: cfg.Audio.File}
+	} else {
+		backend, err := audio.New(cfg.Audio.Backend)
+		if err != nil {
+			return dcfg, err
+		}
+		dcfg.Audio = backend
+	}
+	if cfg.VAD.Enabled {
+		dcfg.VAD = &vad.Detector{
+			SampleRate: 16000,
+			Threshold:  cfg.VAD.Threshold,
+			MinSpeech:  cfg.VAD.MinSpeech,
+			Padding:    cfg.VAD.Padding,
+		}
+	}
+	var filters dsp.Chain
+	if cfg.DSP.RemoveDC {
+		filters = append(filters, dsp.RemoveDC{})
+	}
+	if cfg.DSP.HighPass > 0 {
+		filters = append(filters, dsp.HighPass{Cutoff: cfg.DSP.HighPass})
+	}
+	if cfg.DSP.Gate < 0 {
+		filters = append(filters, dsp.Gate{Threshold: cfg.DSP.Gate})
+	}
+	if cfg.DSP.Normalize != "" {
+		filters = append(filters, dsp.Normalize{
+			RMS:     cfg.DSP.Normalize == "rms",
+			Target:  cfg.DSP.Target,
+			MaxGain: cfg.DSP.MaxGain,
+		})
+	}
+	if len(filters) > 0 {
+		dcfg.DSP = filters
+	}
+	if cfg.Audio.PreRoll > 0 {
+		dcfg.Audio = &audio.Warm{
+			Backend: dcfg.Audio,
+			PreRoll: cfg.Audio.PreRoll,
+			Idle:    cfg.Audio.IdleTimeout,
+		}
+	}
+	var commands voicecmd.Table
+	if cfg.VoiceCommands.File != "" {
+		var err error
+		commands, err = voicecmd.Load(cfg.VoiceCommands.File)
+		if err != nil {
+			return dcfg, fmt.Errorf("load voice commands: %w", err)
+		}
+	} else if cfg.VoiceCommands.Enabled {
+		commands = voicecmd.DefaultTable
+	}
+	rules, err := postproc.Compile(cfg.Rules)
+	if err != nil {
+		return dcfg, err
+	}
+	if cfg.RulesFile != "" {
+		file, err := postproc.LoadRules(cfg.RulesFile)
+		if err != nil {
+			return dcfg, fmt.Errorf("load rules: %w", err)
+		}
+		rules = append(rules, file...)
+	}
+	// the spacing state is shared so that dictations from different profiles are joined
+	if cfg.Spacing.Enabled {
+		spacing.Update(cfg.Spacing.Window, cfg.Spacing.StripPeriod)
+	} else {
+		spacing = nil
+	}
+	for _, pcfg := range cfg.Profiles {
+		p := &daemon.Profile{
+			Name: pcfg.Name,
+			Key:  uint16(pcfg.Key),
+			Transcribe: openai.TranscribeOptions{
+				Model:    pcfg.Model,
+				Language: pcfg.Language,
+				Prompt:   pcfg.Prompt,
+			},
+			Sources:   pcfg.Sources,
+			HandsFree: pcfg.HandsFree,
+		}
+		own, err := postproc.Compile(pcfg.Rules)
+		if err != nil {
+			return dcfg, fmt.Errorf("profile %s: %w", pcfg.Name, err)
+		}
+		var chain postproc.Chain
+		switch pcfg.Mode {
+		case "code":
+			chain = append(chain, postproc.Code{})
+			chain = append(chain, own...)
+		case "", "prose":
+			chain = append(chain, slices.Clone(rules)...)
+			chain = append(chain, own...)
+			if pcfg.LLM != "" {
+				chain = append(chain, &postproc.LLM{
+					Client:  client,
+					Model:   cfg.LLM.Model,
+					Prompt:  pcfg.LLM,
+					Timeout: cfg.LLM.Timeout,
+					Log:     log,
+				})
+			}
+			if spacing != nil {
+				chain = append(chain, spacing)
+			}
+		}
+		if len(chain) > 0 {
+			p.PostProcess = chain
+		}
+		switch pcfg.Output {
+		case "", "type":
+			p.Output = &output.Keyboard{Device: keyboard, Commands: commands}
+		case "paste":
+			p.Output = &output.Paste{Device: keyboard}
+		case "clipboard":
+			p.Output = output.Clipboard{}
+		}
+		dcfg.Profiles = append(dcfg.Profiles, p)
+	}
+	return dcfg, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/icholy/whisperd/internal/config"
+	"github.com/icholy/whisperd/internal/postproc"
+)
+
+func TestBuildMode(t *testing.T) {
+	cfg := config.Default()
+	cfg.Audio.File = "test.wav"
+	cfg.Profiles = []config.Profile{
+		{Name: "prose"},
+		{Name: "explicit", Mode: "prose"},
+		{Name: "code", Mode: "code"},
+	}
+	dcfg, err := build(cfg, nil, &postproc.Spacing{}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const text = "Camel case user id, open paren."
+	want := map[string]string{
+		"prose":    text,
+		"explicit": text,
+		"code":     "userId(",
+	}
+	for _, p := range dcfg.Profiles {
+		got := text
+		if p.PostProcess != nil {
+			got, err = p.PostProcess.Process(context.Background(), text)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got != want[p.Name] {
+			t.Errorf("%s: got %q, want %q", p.Name, got, want[p.Name])
+		}
+	}
+}
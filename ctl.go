@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/icholy/whisperd/internal/control"
+)
+
+// ctl implements the "whisperd ctl" subcommand which sends a command to a
+// running daemon over the control socket.
+func ctl(args []string) {
+	fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+	socketPath := fs.String("socket", control.SocketPath(), "control socket path")
+	timeout := fs.Duration("timeout", time.Minute, "maximum time to wait for a response")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: whisperd ctl [flags] <command> [profile|source]\n\n")
+		fmt.Fprintf(fs.Output(), "commands: start, stop, toggle, cancel, status, last, repeat, reload, pause, resume, select, source, handsfree\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	resp, err := control.Send(ctx, *socketPath, control.Request{
+		Command: fs.Arg(0),
+		Profile: fs.Arg(1),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "whisperd: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.Error != "" {
+		fmt.Fprintf(os.Stderr, "whisperd: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	if resp.Status != "" {
+		fmt.Println(resp.Status)
+	}
+	if resp.Text != "" {
+		fmt.Println(resp.Text)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/icholy/whisperd/internal/config"
+)
+
+// profileFlags implements flag.Value for the repeatable -profile flag.
+// Each value is a comma separated list of name=value pairs. Ex:
+//
+//	key=184,name=french,language=fr,output=clipboard
+type profileFlags []config.Profile
+
+func (p *profileFlags) String() string {
+	return fmt.Sprint(*p)
+}
+
+func (p *profileFlags) Set(s string) error {
+	var profile config.Profile
+	for _, field := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("invalid field %q: expected name=value", field)
+		}
+		switch name {
+		case "key":
+			if key, err := strconv.ParseUint(value, 10, 16); err == nil {
+				profile.Key = config.Key(key)
+			} else if err := profile.Key.UnmarshalTOML(value); err != nil {
+				return err
+			}
+		case "name":
+			profile.Name = value
+		case "language":
+			profile.Language = value
+		case "model":
+			profile.Model = value
+		case "prompt":
+			profile.Prompt = value
+		case "llm":
+			profile.LLM = value
+		case "mode":
+			profile.Mode = value
+		case "output":
+			profile.Output = value
+		case "source":
+			profile.Sources = append(profile.Sources, value)
+		case "handsfree":
+			handsFree, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid handsfree value %q: %w", value, err)
+			}
+			profile.HandsFree = handsFree
+		default:
+			return fmt.Errorf("unknown field %q", name)
+		}
+	}
+	if profile.Key == 0 && profile.Name == "" {
+		return fmt.Errorf("missing key or name")
+	}
+	if profile.Name == "" {
+		profile.Name = strconv.Itoa(int(profile.Key))
+	}
+	*p = append(*p, profile)
+	return nil
+}
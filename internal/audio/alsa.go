@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ALSA records with arecord. The target is a PCM device name such as hw:1,0.
+var ALSA = Command{
+	Name: "arecord",
+	Args: func(opt Options) []string {
+		args := []string{
+			"--quiet",
+			"--file-type", "raw",
+			"--format", "S16_LE",
+			"--rate", strconv.Itoa(opt.SampleRate),
+			"--channels", strconv.Itoa(opt.NumChannels),
+		}
+		if opt.Target != "" {
+			args = append(args, "--device", opt.Target)
+		}
+		return append(args, "-")
+	},
+	Sources: alsaSources,
+}
+
+// alsaSources lists the capture PCMs using arecord -L. Each device name is
+// followed by indented description lines.
+func alsaSources(ctx context.Context) ([]Source, error) {
+	out, err := exec.CommandContext(ctx, "arecord", "-L").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arecord: %w", err)
+	}
+	var sources []Source
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "" || line == "null":
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			sources = append(sources, Source{Name: line, Description: line})
+		case len(sources) > 0:
+			// use the first description line
+			s := &sources[len(sources)-1]
+			if s.Description == s.Name {
+				s.Description = strings.TrimSpace(line)
+			}
+		}
+	}
+	return sources, sc.Err()
+}
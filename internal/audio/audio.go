@@ -0,0 +1,223 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icholy/whisperd/internal/flac"
+	"github.com/icholy/whisperd/internal/wav"
+)
+
+// Options specifies the audio recording parameters.
+// Samples are always signed 16-bit little endian.
+type Options struct {
+	SampleRate  int
+	NumChannels int
+	Target      string // device to record from, the default device if empty
+	// Level is called with the level of every 50ms of audio while recording.
+	// It is called from a separate goroutine and must not block.
+	Level func(Level)
+	// Stream is how much audio the stream of the recording holds for readers
+	// which fall behind, 10s if zero.
+	Stream time.Duration
+	// StreamOnly doesn't keep the samples for PCM, which returns nothing.
+	// It's used for captures which run for a long time.
+	StreamOnly bool
+}
+
+// bytesPerSecond returns the size of a second of audio.
+func (o Options) bytesPerSecond() int {
+	return o.SampleRate * o.NumChannels * 2
+}
+
+// Backend starts recordings.
+type Backend interface {
+	Record(ctx context.Context, opt Options) (Recorder, error)
+}
+
+// Recorder is a recording in progress.
+type Recorder interface {
+	// Stop ends the recording.
+	Stop() error
+	// PCM returns the recorded samples, it must only be called after Stop.
+	PCM() []byte
+	// Options returns the options the recording was started with.
+	Options() Options
+	// Stream returns a reader of the samples as they're recorded. It starts
+	// at the beginning of the recording, or at the oldest samples still in
+	// the stream, and returns io.EOF after Stop.
+	Stream() *RingReader
+}
+
+// WriteWAV writes samples recorded with opt as a WAV file.
+func WriteWAV(w io.Writer, pcm []byte, opt Options) error {
+	return wav.Write(w, pcm, wav.Options{
+		SampleRate:  opt.SampleRate,
+		NumChannels: opt.NumChannels,
+	})
+}
+
+// WriteFLAC writes samples recorded with opt as a FLAC file.
+func WriteFLAC(w io.Writer, pcm []byte, opt Options) error {
+	return flac.Write(w, pcm, flac.Options{
+		SampleRate:  opt.SampleRate,
+		NumChannels: opt.NumChannels,
+	})
+}
+
+// Encoder writes samples recorded with opt in a file format.
+type Encoder func(w io.Writer, pcm []byte, opt Options) error
+
+// Formats are the file formats recordings can be uploaded in, by file extension.
+var Formats = map[string]Encoder{
+	"wav":  WriteWAV,
+	"flac": WriteFLAC,
+}
+
+// Source is a device which can be recorded from.
+type Source struct {
+	Name        string // used as Options.Target
+	Description string
+}
+
+// Lister is implemented by backends which can list their sources.
+type Lister interface {
+	ListSources(ctx context.Context) ([]Source, error)
+}
+
+// ListSources lists the sources of the backend.
+func ListSources(ctx context.Context, b Backend) ([]Source, error) {
+	l, ok := b.(Lister)
+	if !ok {
+		return nil, errors.New("the audio backend can't list sources")
+	}
+	return l.ListSources(ctx)
+}
+
+// Match returns the first source which matches one of the patterns, trying
+// the patterns in order. A pattern matches a source with the same name or a
+// name or description which contains it, ignoring case.
+func Match(sources []Source, patterns []string) (Source, bool) {
+	for _, p := range patterns {
+		for _, s := range sources {
+			if s.Name == p {
+				return s, true
+			}
+		}
+		p = strings.ToLower(p)
+		for _, s := range sources {
+			if strings.Contains(strings.ToLower(s.Name), p) || strings.Contains(strings.ToLower(s.Description), p) {
+				return s, true
+			}
+		}
+	}
+	return Source{}, false
+}
+
+// Backends are the backends which can be selected by name.
+var Backends = map[string]Backend{
+	"pipewire":   PipeWire,
+	"pulseaudio": PulseAudio,
+	"alsa":       ALSA,
+}
+
+// Detect returns the first backend whose recording program is installed,
+// trying PipeWire, PulseAudio and ALSA in that order.
+func Detect() (Backend, error) {
+	for _, c := range []Command{PipeWire, PulseAudio, ALSA} {
+		if _, err := exec.LookPath(c.Name); err == nil {
+			return c, nil
+		}
+	}
+	return nil, errors.New("no audio backend found, install pw-cat, parec or arecord")
+}
+
+// New returns the named backend. The name "auto" detects the backend.
+func New(name string) (Backend, error) {
+	if name == "" || name == "auto" {
+		return Detect()
+	}
+	b, ok := Backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio backend: %q", name)
+	}
+	return b, nil
+}
+
+// buffer stores the recorded samples and streams them to the level meter.
+type buffer struct {
+	opt    Options
+	stream *Ring
+	meter  sync.WaitGroup
+
+	mu  sync.Mutex
+	pcm bytes.Buffer
+}
+
+func newBuffer(opt Options) *buffer {
+	size := opt.Stream
+	if size <= 0 {
+		size = 10 * time.Second
+	}
+	b := &buffer{
+		opt:    opt,
+		stream: NewRing(max(1, int(size.Seconds()*float64(opt.bytesPerSecond())))),
+	}
+	if opt.Level != nil {
+		lvl := &levelMeter{
+			window: opt.SampleRate * opt.NumChannels / 20,
+			report: opt.Level,
+		}
+		r := b.stream.Reader()
+		b.meter.Add(1)
+		go func() {
+			defer b.meter.Done()
+			for {
+				_, err := io.Copy(lvl, r)
+				if !errors.Is(err, ErrOverrun) {
+					return
+				}
+			}
+		}()
+	}
+	return b
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	if !b.opt.StreamOnly {
+		b.mu.Lock()
+		b.pcm.Write(p)
+		b.mu.Unlock()
+	}
+	return b.stream.Write(p)
+}
+
+// close ends the stream and waits for the level meter to read it.
+func (b *buffer) close() {
+	b.stream.Close()
+	b.meter.Wait()
+}
+
+// Stream returns a reader of the recorded samples.
+func (b *buffer) Stream() *RingReader {
+	return b.stream.Reader()
+}
+
+// PCM returns the recorded samples.
+func (b *buffer) PCM() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pcm.Bytes()
+}
+
+// Options returns the recording options.
+func (b *buffer) Options() Options {
+	return b.opt
+}
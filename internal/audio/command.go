@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Command is a backend which records with a program that writes raw
+// samples to stdout until it receives SIGTERM.
+type Command struct {
+	Name    string
+	Args    func(opt Options) []string
+	Sources func(ctx context.Context) ([]Source, error)
+}
+
+// ListSources lists the devices which can be recorded from.
+func (c Command) ListSources(ctx context.Context) ([]Source, error) {
+	if c.Sources == nil {
+		return nil, errors.New(c.Name + " can't list sources")
+	}
+	return c.Sources(ctx)
+}
+
+// Record starts the program.
+func (c Command) Record(ctx context.Context, opt Options) (Recorder, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args(opt)...)
+	buf := newBuffer(opt)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = buf
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &process{buffer: buf, cmd: cmd}, nil
+}
+
+// process is a recording made by a Command.
+type process struct {
+	*buffer
+	cmd *exec.Cmd
+}
+
+// Stop terminates the program and waits for it to exit and its output to be copied.
+func (p *process) Stop() error {
+	defer p.close()
+	// the program may have exited on its own
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	if err := p.cmd.Wait(); err != nil {
+		// the program exits because of the signal
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/icholy/whisperd/internal/wav"
+)
+
+// File is a backend which plays back a WAV file for every recording.
+// The file must match the recording options.
+type File struct {
+	Path string
+}
+
+// Record reads the file.
+func (f File) Record(ctx context.Context, opt Options) (Recorder, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	pcm, wopt, err := wav.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	if wopt.SampleRate != opt.SampleRate || wopt.NumChannels != opt.NumChannels {
+		return nil, fmt.Errorf("%s: want %dHz with %d channels, got %dHz with %d channels",
+			f.Path, opt.SampleRate, opt.NumChannels, wopt.SampleRate, wopt.NumChannels)
+	}
+	return Fake{PCM: pcm}.Record(ctx, opt)
+}
+
+// Fake is a backend which returns the same samples for every recording.
+type Fake struct {
+	PCM []byte
+}
+
+// Record returns a recording of the samples.
+func (f Fake) Record(ctx context.Context, opt Options) (Recorder, error) {
+	p := &playback{buffer: newBuffer(opt), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		p.Write(f.PCM)
+	}()
+	return p, nil
+}
+
+// playback is a recording of samples which are already known.
+type playback struct {
+	*buffer
+	done chan struct{}
+}
+
+// Stop waits for the samples to be written.
+func (p *playback) Stop() error {
+	<-p.done
+	p.close()
+	return nil
+}
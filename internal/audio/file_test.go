@@ -0,0 +1,110 @@
+package audio_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/wav"
+)
+
+func TestFake(t *testing.T) {
+	pcm := make([]byte, 32000)
+	for i := range pcm {
+		pcm[i] = byte(i)
+	}
+	var (
+		mu     sync.Mutex
+		levels int
+	)
+	opt := audio.Options{
+		SampleRate:  16000,
+		NumChannels: 1,
+		Level: func(audio.Level) {
+			mu.Lock()
+			levels++
+			mu.Unlock()
+		},
+	}
+	rec, err := audio.Fake{PCM: pcm}.Record(context.Background(), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := rec.Stream()
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rec.PCM(), pcm) {
+		t.Error("PCM doesn't match the fake samples")
+	}
+	streamed, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(streamed, pcm) {
+		t.Error("stream doesn't match the fake samples")
+	}
+	if got := rec.Options(); got.SampleRate != 16000 || got.NumChannels != 1 {
+		t.Errorf("got options %+v", got)
+	}
+	// the meter has read the whole stream once Stop returns
+	mu.Lock()
+	defer mu.Unlock()
+	if want := 20; levels != want {
+		t.Errorf("got %d levels, want one every 50ms: %d", levels, want)
+	}
+}
+
+func TestFakeStreamOnly(t *testing.T) {
+	pcm := make([]byte, 3200)
+	opt := audio.Options{SampleRate: 16000, NumChannels: 1, StreamOnly: true}
+	rec, err := audio.Fake{PCM: pcm}.Record(context.Background(), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := rec.Stream()
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.PCM()) != 0 {
+		t.Errorf("got %d bytes of PCM, want none", len(rec.PCM()))
+	}
+	streamed, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != len(pcm) {
+		t.Errorf("streamed %d bytes, want %d", len(streamed), len(pcm))
+	}
+}
+
+func TestFile(t *testing.T) {
+	pcm := []byte{1, 0, 2, 0, 3, 0}
+	path := filepath.Join(t.TempDir(), "test.wav")
+	var file bytes.Buffer
+	if err := wav.Write(&file, pcm, wav.Options{SampleRate: 16000, NumChannels: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := audio.File{Path: path}.Record(context.Background(), audio.Options{SampleRate: 16000, NumChannels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rec.PCM(), pcm) {
+		t.Errorf("got %v, want %v", rec.PCM(), pcm)
+	}
+	// the file must match the recording options
+	if _, err := (audio.File{Path: path}).Record(context.Background(), audio.Options{SampleRate: 44100, NumChannels: 1}); err == nil {
+		t.Error("expected an error for a different sample rate")
+	}
+}
@@ -0,0 +1,76 @@
+package audio_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/openai"
+)
+
+// uplink is the simulated upload bandwidth in bits per second.
+const uplink = 2_000_000
+
+// speech returns 16kHz mono PCM which resembles speech: voiced syllables
+// with a wandering pitch and a few harmonics, pauses, and background noise.
+func speech(d time.Duration) []byte {
+	const rate = 16000
+	rng := rand.New(rand.NewSource(1))
+	n := int(d.Seconds() * rate)
+	pcm := make([]byte, 2*n)
+	var phase float64
+	for i := range n {
+		t := float64(i) / rate
+		pitch := 140 + 40*math.Sin(2*math.Pi*0.3*t)
+		phase += 2 * math.Pi * pitch / rate
+		// four syllables a second, with a pause every few seconds
+		envelope := math.Max(0, math.Sin(2*math.Pi*2*t))
+		if math.Mod(t, 3) > 2.4 {
+			envelope = 0
+		}
+		v := 0.0
+		for h := 1.0; h <= 5; h++ {
+			v += math.Sin(h*phase) / h
+		}
+		v = 6000*envelope*v + 60*rng.NormFloat64()
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(v)))
+	}
+	return pcm
+}
+
+func BenchmarkUpload(b *testing.B) {
+	pcm := speech(30 * time.Second)
+	opt := audio.Options{SampleRate: 16000, NumChannels: 1}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		time.Sleep(time.Duration(n*8) * time.Second / uplink)
+		fmt.Fprint(w, `{"text":"hello"}`)
+	}))
+	defer srv.Close()
+	client := openai.Client{APIKey: "test", BaseURL: srv.URL}
+	for _, format := range []string{"wav", "flac"} {
+		b.Run(format, func(b *testing.B) {
+			var size int
+			for b.Loop() {
+				var file bytes.Buffer
+				if err := audio.Formats[format](&file, pcm, opt); err != nil {
+					b.Fatal(err)
+				}
+				size = file.Len()
+				if _, err := client.Transcribe(context.Background(), format, &file, openai.TranscribeOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(size), "upload-bytes")
+		})
+	}
+}
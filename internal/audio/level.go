@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Level is the loudness of a short window of audio.
+// Both values are between 0 and 1, relative to full scale.
+type Level struct {
+	RMS  float64
+	Peak float64
+}
+
+// DB returns the RMS level in dBFS.
+func (l Level) DB() float64 {
+	return 20 * math.Log10(l.RMS)
+}
+
+// levelMeter reports the level of every window of s16 samples written to it.
+type levelMeter struct {
+	window int // samples per report
+	report func(Level)
+
+	odd   []byte // trailing byte of a split sample
+	n     int
+	sumSq float64
+	peak  float64
+}
+
+func (m *levelMeter) Write(p []byte) (int, error) {
+	data := append(m.odd, p...)
+	m.odd = nil
+	if len(data)%2 != 0 {
+		m.odd = []byte{data[len(data)-1]}
+		data = data[:len(data)-1]
+	}
+	for i := 0; i < len(data); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(data[i:]))) / math.MaxInt16
+		m.sumSq += v * v
+		m.peak = max(m.peak, math.Abs(v))
+		m.n++
+		if m.n == m.window {
+			m.report(Level{
+				RMS:  math.Sqrt(m.sumSq / float64(m.n)),
+				Peak: min(m.peak, 1),
+			})
+			m.n, m.sumSq, m.peak = 0, 0, 0
+		}
+	}
+	return len(p), nil
+}
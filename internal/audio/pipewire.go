@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// PipeWire records with pw-cat. The target is a node name or id.
+var PipeWire = Command{
+	Name: "pw-cat",
+	Args: func(opt Options) []string {
+		args := []string{
+			"--record",
+			"--format", "s16",
+			"--rate", strconv.Itoa(opt.SampleRate),
+			"--channels", strconv.Itoa(opt.NumChannels),
+		}
+		if opt.Target != "" {
+			args = append(args, "--target", opt.Target)
+		}
+		return append(args, "-")
+	},
+	Sources: pipewireSources,
+}
+
+// pipewireSources lists the audio input nodes using pw-dump.
+func pipewireSources(ctx context.Context) ([]Source, error) {
+	out, err := exec.CommandContext(ctx, "pw-dump").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pw-dump: %w", err)
+	}
+	var objects []struct {
+		Type string `json:"type"`
+		Info struct {
+			Props map[string]any `json:"props"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, fmt.Errorf("pw-dump: %w", err)
+	}
+	var sources []Source
+	for _, obj := range objects {
+		if obj.Type != "PipeWire:Interface:Node" || obj.Info.Props["media.class"] != "Audio/Source" {
+			continue
+		}
+		name, _ := obj.Info.Props["node.name"].(string)
+		desc, _ := obj.Info.Props["node.description"].(string)
+		if desc == "" {
+			desc = name
+		}
+		sources = append(sources, Source{Name: name, Description: desc})
+	}
+	return sources, nil
+}
@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PulseAudio records with parec. The target is a source name.
+var PulseAudio = Command{
+	Name: "parec",
+	Args: func(opt Options) []string {
+		args := []string{
+			"--raw",
+			"--format=s16le",
+			"--rate=" + strconv.Itoa(opt.SampleRate),
+			"--channels=" + strconv.Itoa(opt.NumChannels),
+		}
+		if opt.Target != "" {
+			args = append(args, "--device="+opt.Target)
+		}
+		return args
+	},
+	Sources: pulseaudioSources,
+}
+
+// pulseaudioSources lists the sources using pactl, skipping the monitors of sinks.
+func pulseaudioSources(ctx context.Context) ([]Source, error) {
+	out, err := exec.CommandContext(ctx, "pactl", "list", "short", "sources").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pactl: %w", err)
+	}
+	var sources []Source
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		// index, name, driver, sample spec, state
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) < 2 || strings.HasSuffix(fields[1], ".monitor") {
+			continue
+		}
+		sources = append(sources, Source{Name: fields[1], Description: fields[1]})
+	}
+	return sources, sc.Err()
+}
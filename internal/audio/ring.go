@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrOverrun is returned by a RingReader which fell so far behind that the
+// samples it hadn't read yet were overwritten. The reader continues with the
+// oldest samples in the ring.
+var ErrOverrun = errors.New("audio: ring buffer overrun")
+
+// Ring is a bounded buffer of the most recently written bytes. It can be
+// read by any number of RingReaders while it's being written.
+// It is safe for concurrent use.
+type Ring struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	data    []byte
+	written int64 // total number of bytes written
+	closed  bool
+}
+
+// NewRing returns a ring which holds up to size bytes.
+func NewRing(size int) *Ring {
+	r := &Ring{data: make([]byte, size)}
+	r.cond.L = &r.mu
+	return r
+}
+
+// Write appends p to the ring, overwriting the oldest bytes if it's full.
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n := len(p)
+	if len(p) > len(r.data) {
+		// only the tail fits
+		r.written += int64(len(p) - len(r.data))
+		p = p[len(p)-len(r.data):]
+	}
+	for len(p) > 0 {
+		i := int(r.written % int64(len(r.data)))
+		c := copy(r.data[i:], p)
+		p = p[c:]
+		r.written += int64(c)
+	}
+	r.cond.Broadcast()
+	return n, nil
+}
+
+// Close wakes up the readers, which return io.EOF once they've read everything.
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.cond.Broadcast()
+	return nil
+}
+
+// Written returns the total number of bytes written to the ring.
+func (r *Ring) Written() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.written
+}
+
+// Bytes returns a copy of the bytes in the ring, oldest first.
+func (r *Ring) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := make([]byte, r.written-r.oldest())
+	i := int(r.oldest() % int64(len(r.data)))
+	n := copy(b, r.data[i:])
+	copy(b[n:], r.data)
+	return b
+}
+
+// Reader returns a reader which starts at the oldest byte in the ring.
+func (r *Ring) Reader() *RingReader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &RingReader{ring: r, off: r.oldest()}
+}
+
+// oldest returns the offset of the oldest byte in the ring, the ring must be locked.
+func (r *Ring) oldest() int64 {
+	return max(0, r.written-int64(len(r.data)))
+}
+
+// RingReader reads from a Ring as it's written.
+type RingReader struct {
+	ring *Ring
+	off  int64
+}
+
+// Read blocks until bytes are available or the ring is closed.
+func (rr *RingReader) Read(p []byte) (int, error) {
+	r := rr.ring
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for rr.off == r.written && !r.closed {
+		r.cond.Wait()
+	}
+	if oldest := r.oldest(); rr.off < oldest {
+		rr.off = oldest
+		return 0, ErrOverrun
+	}
+	if rr.off == r.written {
+		return 0, io.EOF
+	}
+	available := r.written - rr.off
+	if int64(len(p)) > available {
+		p = p[:available]
+	}
+	i := int(rr.off % int64(len(r.data)))
+	n := copy(p, r.data[i:])
+	n += copy(p[n:], r.data)
+	rr.off += int64(n)
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRingWrap(t *testing.T) {
+	r := NewRing(8)
+	rr := r.Reader()
+	buf := make([]byte, 5)
+	// each write wraps around the end of the ring at a different offset,
+	// and a single read returns both parts in order
+	for _, chunk := range []string{"abcde", "fghij", "klmno"} {
+		r.Write([]byte(chunk))
+		n, err := rr.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != chunk {
+			t.Errorf("got %q, want %q", got, chunk)
+		}
+	}
+}
+
+func TestRingOverwrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"partial", []string{"abc"}, "abc"},
+		{"full", []string{"abcd", "efgh"}, "abcdefgh"},
+		{"past capacity", []string{"abcdef", "ghijk"}, "defghijk"},
+		{"single write past capacity", []string{"abcdefghijkl"}, "efghijkl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing(8)
+			var written int64
+			for _, w := range tt.writes {
+				n, err := r.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+				written += int64(n)
+			}
+			if got := string(r.Bytes()); got != tt.want {
+				t.Errorf("Bytes() = %q, want %q", got, tt.want)
+			}
+			if got := r.Written(); got != written {
+				t.Errorf("Written() = %d, want %d", got, written)
+			}
+			// new readers start at the oldest byte
+			r.Close()
+			got, err := io.ReadAll(r.Reader())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingOverrun(t *testing.T) {
+	r := NewRing(8)
+	slow := r.Reader()
+	buf := make([]byte, 2)
+	r.Write([]byte("abcd"))
+	if n, err := slow.Read(buf); err != nil || string(buf[:n]) != "ab" {
+		t.Fatalf("Read() = %q, %v", buf[:n], err)
+	}
+	// "cd" is overwritten before the reader gets to it
+	r.Write([]byte("efghijkl"))
+	if _, err := slow.Read(buf); !errors.Is(err, ErrOverrun) {
+		t.Fatalf("got %v, want %v", err, ErrOverrun)
+	}
+	// the reader continues with the oldest bytes
+	r.Close()
+	rest, err := io.ReadAll(slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "efghijkl"; string(rest) != want {
+		t.Errorf("got %q, want %q", rest, want)
+	}
+}
+
+func TestRingReadAcrossWrap(t *testing.T) {
+	data := make([]byte, 1<<16)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	r := NewRing(1024)
+	rr := r.Reader()
+	var got []byte
+	buf := make([]byte, 700)
+	// odd sized writes and reads cross the end of the ring at many offsets
+	for p := data; len(p) > 0; {
+		n := min(len(p), 999)
+		r.Write(p[:n])
+		p = p[n:]
+		for int64(len(got)) < r.Written() {
+			n, err := rr.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, buf[:n]...)
+		}
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes which don't match the %d written", len(got), len(data))
+	}
+	r.Close()
+	if _, err := rr.Read(buf); err != io.EOF {
+		t.Errorf("read after close: got %v, want %v", err, io.EOF)
+	}
+	if _, err := r.Write([]byte("x")); err != io.ErrClosedPipe {
+		t.Errorf("write after close: got %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestRingBlockingRead(t *testing.T) {
+	r := NewRing(16)
+	rr := r.Reader()
+	done := make(chan []byte)
+	go func() {
+		got, _ := io.ReadAll(rr)
+		done <- got
+	}()
+	r.Write([]byte("hello "))
+	r.Write([]byte("world"))
+	r.Close()
+	if got := string(<-done); got != "hello world" {
+		t.Errorf("got %q, want %q", got, "hello world")
+	}
+}
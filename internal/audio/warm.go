@@ -0,0 +1,173 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Warm is a backend which keeps capturing between recordings, so that
+// every recording starts with the last PreRoll of audio and isn't clipped
+// by the startup latency of the underlying backend.
+type Warm struct {
+	Backend Backend
+	PreRoll time.Duration
+	// Idle is how long the capture keeps running after a recording,
+	// releasing the microphone. Zero keeps it running until Close.
+	Idle time.Duration
+
+	mu      sync.Mutex
+	capture Recorder
+	opt     Options // options of the capture
+	preroll *Ring
+	active  *warmRecording
+	idle    *time.Timer
+	closed  bool
+}
+
+// Record starts a recording with the pre-roll, starting the capture if it
+// isn't running or was started with different options.
+func (w *Warm) Record(ctx context.Context, opt Options) (Recorder, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, errors.New("audio: backend closed")
+	}
+	if w.active != nil {
+		return nil, errors.New("audio: already recording")
+	}
+	if w.idle != nil {
+		w.idle.Stop()
+	}
+	if w.capture != nil && !w.opt.same(opt) {
+		w.stopCapture()
+	}
+	if w.capture == nil {
+		// the capture outlives the recording, so it doesn't use its context
+		copt := Options{
+			SampleRate:  opt.SampleRate,
+			NumChannels: opt.NumChannels,
+			Target:      opt.Target,
+			StreamOnly:  true,
+		}
+		capture, err := w.Backend.Record(context.WithoutCancel(ctx), copt)
+		if err != nil {
+			return nil, err
+		}
+		w.capture = capture
+		w.opt = copt
+		w.preroll = NewRing(max(1, int(w.PreRoll.Seconds()*float64(opt.bytesPerSecond()))))
+		go w.pump(capture, w.preroll)
+	}
+	rec := &warmRecording{buffer: newBuffer(opt), warm: w}
+	rec.Write(w.preroll.Bytes())
+	w.active = rec
+	return rec, nil
+}
+
+// Close stops the capture once the recording in progress is stopped.
+func (w *Warm) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.idle != nil {
+		w.idle.Stop()
+	}
+	if w.active == nil {
+		w.stopCapture()
+	}
+	return nil
+}
+
+// stopCapture stops the capture, w must be locked.
+func (w *Warm) stopCapture() {
+	if w.capture == nil {
+		return
+	}
+	capture := w.capture
+	w.capture = nil
+	go capture.Stop()
+	if w.active != nil {
+		w.active.err = errors.New("audio: capture stopped")
+		w.active.close()
+		w.active = nil
+	}
+}
+
+// pump copies the captured samples to the pre-roll and the active recording.
+func (w *Warm) pump(capture Recorder, preroll *Ring) {
+	r := capture.Stream()
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			w.mu.Lock()
+			if w.capture == capture {
+				preroll.Write(buf[:n])
+				if w.active != nil {
+					w.active.Write(buf[:n])
+				}
+			}
+			w.mu.Unlock()
+		}
+		if errors.Is(err, ErrOverrun) {
+			continue
+		}
+		if err != nil {
+			break
+		}
+	}
+	// the capture ended on its own if it's still current
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.capture == capture {
+		w.stopCapture()
+	}
+}
+
+// release stops the capture if it's still idle.
+func (w *Warm) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.active == nil {
+		w.stopCapture()
+	}
+}
+
+// warmRecording is a recording made by Warm.
+type warmRecording struct {
+	*buffer
+	warm *Warm
+	err  error // set when the capture stopped during the recording
+}
+
+// Stop ends the recording, the capture keeps running for the idle timeout.
+func (r *warmRecording) Stop() error {
+	w := r.warm
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.active != r {
+		// the capture stopped and closed the recording
+		return r.err
+	}
+	w.active = nil
+	r.close()
+	if w.closed {
+		w.stopCapture()
+		return nil
+	}
+	if w.Idle > 0 {
+		if w.idle == nil {
+			w.idle = time.AfterFunc(w.Idle, w.release)
+		} else {
+			w.idle.Reset(w.Idle)
+		}
+	}
+	return nil
+}
+
+// same reports whether a capture with o can be used for a recording with other.
+func (o Options) same(other Options) bool {
+	return o.SampleRate == other.SampleRate && o.NumChannels == other.NumChannels && o.Target == other.Target
+}
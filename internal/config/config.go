@@ -0,0 +1,458 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/control"
+	"github.com/icholy/whisperd/internal/inputcodes"
+	"github.com/icholy/whisperd/internal/postproc"
+	"github.com/icholy/whisperd/internal/secret"
+)
+
+// Config is the whisperd configuration.
+type Config struct {
+	Inputs    []string `toml:"inputs"`
+	RepeatKey Key      `toml:"repeat_key"`
+	Socket    string   `toml:"socket"`
+	Tray      bool     `toml:"tray"`
+	DBus      bool     `toml:"dbus"`
+	// ErrorTimeout is how long errors are shown in the tray, zero until the next dictation.
+	ErrorTimeout time.Duration `toml:"error_timeout"`
+
+	Audio         Audio         `toml:"audio"`
+	OpenAI        OpenAI        `toml:"openai"`
+	LLM           LLM           `toml:"llm"`
+	Spacing       Spacing       `toml:"spacing"`
+	VoiceCommands VoiceCommands `toml:"voicecmd"`
+	Uinput        Uinput        `toml:"uinput"`
+	Notify        Notify        `toml:"notify"`
+	VAD           VAD           `toml:"vad"`
+	HandsFree     HandsFree     `toml:"handsfree"`
+	DSP           DSP           `toml:"dsp"`
+
+	// Rules are applied to prose profiles before their own rules.
+	Rules     []postproc.Rule `toml:"rule"`
+	RulesFile string          `toml:"rules_file"`
+
+	Profiles []Profile `toml:"profile"`
+}
+
+// Audio configures recording.
+type Audio struct {
+	// Backend is auto, pipewire, pulseaudio or alsa.
+	Backend string `toml:"backend"`
+	// Device is the source to record from, the default source if empty.
+	Device string `toml:"device"`
+	// PreRoll keeps capturing between recordings and prepends this much
+	// audio to every recording, zero to disable.
+	PreRoll time.Duration `toml:"preroll"`
+	// IdleTimeout stops the pre-roll capture after this long without
+	// dictation, zero to keep it running.
+	IdleTimeout time.Duration `toml:"idle_timeout"`
+	// File is a WAV file which is used instead of recording, for testing.
+	File string `toml:"file"`
+	Dump bool   `toml:"dump"`
+	// SilenceWarning is how long the microphone can be silent while
+	// recording before the tray shows a warning, zero to disable.
+	SilenceWarning time.Duration `toml:"silence_warning"`
+	// MaxDuration stops recordings which run longer, zero for no limit.
+	MaxDuration time.Duration `toml:"max_duration"`
+	// ChunkDuration splits longer recordings into chunks which are
+	// transcribed separately, zero to upload them whole.
+	ChunkDuration time.Duration `toml:"chunk_duration"`
+	// ChunkParallel is the number of chunks transcribed at once.
+	ChunkParallel int `toml:"chunk_parallel"`
+	// Format is the file format recordings are uploaded in, wav or flac.
+	Format string `toml:"format"`
+}
+
+// VAD configures voice activity detection.
+type VAD struct {
+	Enabled bool `toml:"enabled"`
+	// Threshold is how far above the noise floor speech is, in dB.
+	Threshold float64       `toml:"threshold"`
+	MinSpeech time.Duration `toml:"min_speech"`
+	Padding   time.Duration `toml:"padding"`
+}
+
+// DSP configures the audio processing applied before uploading.
+type DSP struct {
+	RemoveDC bool `toml:"remove_dc"`
+	// HighPass is the cutoff frequency of the high-pass filter in Hz, zero to disable.
+	HighPass float64 `toml:"highpass"`
+	// Gate is the noise gate threshold in dBFS, zero to disable.
+	Gate float64 `toml:"gate"`
+	// Normalize is empty, peak or rms.
+	Normalize string `toml:"normalize"`
+	// Target is the normalized level in dBFS, zero for the default.
+	Target  float64 `toml:"target"`
+	MaxGain float64 `toml:"max_gain"`
+}
+
+// HandsFree configures hands-free dictation.
+type HandsFree struct {
+	// EndSilence is the pause which ends an utterance.
+	EndSilence time.Duration `toml:"end_silence"`
+	// Timeout stops listening after this long without speech, zero to keep listening.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// Notify configures desktop notifications.
+type Notify struct {
+	Enabled     bool          `toml:"enabled"`
+	Transcripts bool          `toml:"transcripts"`
+	Interval    time.Duration `toml:"interval"`
+}
+
+// OpenAI configures the API client.
+type OpenAI struct {
+	Key     secret.Secret `toml:"key"`
+	KeyFile string        `toml:"key_file"`
+	// SecretService looks up the key in the freedesktop Secret Service
+	// using the attributes application=whisperd.
+	SecretService bool   `toml:"secret_service"`
+	BaseURL       string `toml:"base_url"`
+}
+
+// SecretServiceTimeout bounds the Secret Service lookup, which includes
+// waiting for the user to unlock the keyring.
+const SecretServiceTimeout = time.Minute
+
+// ResolveKey finds the API key. The first of these is used:
+//
+//  1. the key set in the configuration
+//  2. the key file, relative to $CREDENTIALS_DIRECTORY if set
+//  3. the OPENAI_API_KEY environment variable
+//  4. the systemd credential named openai-key
+//  5. the Secret Service, if enabled
+func (o *OpenAI) ResolveKey(ctx context.Context) error {
+	return o.resolveKey(ctx, lookupKey)
+}
+
+// resolveKey is ResolveKey with the Secret Service lookup done by lookup.
+func (o *OpenAI) resolveKey(ctx context.Context, lookup func(context.Context) (secret.Secret, error)) error {
+	if o.Key != "" {
+		return nil
+	}
+	if o.KeyFile != "" {
+		key, err := secret.ReadFile(o.KeyFile, "OPENAI_API_KEY")
+		if err != nil {
+			return fmt.Errorf("read key file: %w", err)
+		}
+		o.Key = key
+		return nil
+	}
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		o.Key = secret.Secret(key)
+		return nil
+	}
+	if key, ok := secret.Credential("openai-key"); ok {
+		o.Key = key
+		return nil
+	}
+	if o.SecretService {
+		key, err := lookup(ctx)
+		if err != nil {
+			return fmt.Errorf("lookup key: %w", err)
+		}
+		o.Key = key
+	}
+	return nil
+}
+
+// lookupKey looks up the key in the Secret Service.
+var lookupKey = func(ctx context.Context) (secret.Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, SecretServiceTimeout)
+	defer cancel()
+	return secret.Lookup(ctx, map[string]string{"application": "whisperd"})
+}
+
+// KeyCache remembers the key found in the Secret Service, so that reloading
+// the configuration doesn't ask the user to unlock the keyring again.
+type KeyCache struct {
+	mu  sync.Mutex
+	key secret.Secret
+}
+
+// ResolveKey resolves the key like OpenAI.ResolveKey. The key file and
+// credentials are read again every time, and only the Secret Service lookup
+// is reused.
+func (c *KeyCache) ResolveKey(ctx context.Context, o *OpenAI) error {
+	return o.resolveKey(ctx, func(ctx context.Context) (secret.Secret, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.key != "" {
+			return c.key, nil
+		}
+		key, err := lookupKey(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.key = key
+		return key, nil
+	})
+}
+
+// LLM configures the chat model used for cleanup.
+type LLM struct {
+	Model   string        `toml:"model"`
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// Spacing configures joining of consecutive dictations.
+type Spacing struct {
+	Enabled     bool          `toml:"enabled"`
+	Window      time.Duration `toml:"window"`
+	StripPeriod int           `toml:"strip_period"`
+}
+
+// VoiceCommands configures spoken key commands.
+type VoiceCommands struct {
+	Enabled bool   `toml:"enabled"`
+	File    string `toml:"file"`
+}
+
+// Uinput configures the virtual keyboard.
+// Changes take effect after a restart.
+type Uinput struct {
+	Name    string `toml:"name"`
+	Vendor  uint16 `toml:"vendor"`
+	Product uint16 `toml:"product"`
+}
+
+// Profile binds a hotkey to transcription, post-processing and output settings.
+type Profile struct {
+	Name     string `toml:"name"`
+	Key      Key    `toml:"key"`
+	Language string `toml:"language"`
+	Model    string `toml:"model"`
+	Prompt   string `toml:"prompt"`
+	LLM      string `toml:"llm"`
+	Mode     string `toml:"mode"`
+	Output   string `toml:"output"`
+	// Sources are the preferred audio sources, the first one that is present is used.
+	Sources []string `toml:"sources"`
+	// HandsFree makes the hotkey start and stop hands-free dictation.
+	HandsFree bool `toml:"hands_free"`
+
+	Rules []postproc.Rule `toml:"rule"`
+}
+
+// Key is a key code. In the configuration file it can be written as a
+// number or as a key name without the KEY_ prefix, e.g. "F13".
+type Key uint16
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (k *Key) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 || v > inputcodes.KEY_MAX {
+			return fmt.Errorf("key code out of range: %d", v)
+		}
+		*k = Key(v)
+		return nil
+	case string:
+		code, ok := inputcodes.KeyNames[strings.TrimPrefix(strings.ToUpper(v), "KEY_")]
+		if !ok {
+			return fmt.Errorf("unknown key: %q", v)
+		}
+		*k = Key(code)
+		return nil
+	default:
+		return fmt.Errorf("invalid key: %v", v)
+	}
+}
+
+// maxChunkDuration is the longest 16kHz mono WAV that fits in the 25MB
+// upload limit of the transcription API.
+const maxChunkDuration = 13 * time.Minute
+
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		Socket:       control.SocketPath(),
+		Tray:         true,
+		DBus:         true,
+		ErrorTimeout: 10 * time.Second,
+		Audio: Audio{
+			IdleTimeout:    5 * time.Minute,
+			SilenceWarning: 3 * time.Second,
+			MaxDuration:    10 * time.Minute,
+			ChunkDuration:  5 * time.Minute,
+			ChunkParallel:  1,
+			Format:         "wav",
+		},
+		LLM: LLM{
+			Model:   "gpt-4o-mini",
+			Timeout: 5 * time.Second,
+		},
+		Spacing: Spacing{
+			Window: time.Minute,
+		},
+		Uinput: Uinput{
+			Name:    "whisperd",
+			Vendor:  0x1234,
+			Product: 0x5678,
+		},
+		Notify: Notify{
+			Interval: 30 * time.Second,
+		},
+		HandsFree: HandsFree{
+			EndSilence: 800 * time.Millisecond,
+			Timeout:    30 * time.Second,
+		},
+	}
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/whisperd/config.toml.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "whisperd", "config.toml")
+}
+
+// Load decodes the file at path into cfg. Keys missing from the file keep
+// their value in cfg.
+func Load(path string, cfg *Config) error {
+	md, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("%s: unknown keys: %v", path, undecoded)
+	}
+	return nil
+}
+
+// Validate checks the configuration and reports every problem found.
+func (c *Config) Validate() error {
+	var errs []error
+	if len(c.Inputs) == 0 && c.Socket == "" && !c.DBus {
+		errs = append(errs, errors.New("no input devices configured and both the control socket and the D-Bus service are disabled"))
+	}
+	if c.OpenAI.Key == "" && c.OpenAI.BaseURL == "" {
+		errs = append(errs, errors.New("no api key found"))
+	}
+	if len(c.Uinput.Name) >= 80 {
+		errs = append(errs, fmt.Errorf("uinput name is too long: %q", c.Uinput.Name))
+	}
+	if len(c.Profiles) == 0 {
+		errs = append(errs, errors.New("no profiles configured"))
+	}
+	if _, ok := audio.Backends[c.Audio.Backend]; !ok && c.Audio.Backend != "" && c.Audio.Backend != "auto" {
+		errs = append(errs, fmt.Errorf("invalid audio backend %q: expected auto, pipewire, pulseaudio or alsa", c.Audio.Backend))
+	}
+	if _, ok := audio.Formats[c.Audio.Format]; !ok && c.Audio.Format != "" {
+		errs = append(errs, fmt.Errorf("invalid audio format %q: expected wav or flac", c.Audio.Format))
+	}
+	switch c.DSP.Normalize {
+	case "", "peak", "rms":
+	default:
+		errs = append(errs, fmt.Errorf("invalid dsp normalize mode %q: expected peak or rms", c.DSP.Normalize))
+	}
+	if c.DSP.Gate > 0 {
+		errs = append(errs, fmt.Errorf("invalid dsp gate threshold %v: expected a negative level in dBFS", c.DSP.Gate))
+	}
+	if c.Audio.ChunkDuration > maxChunkDuration {
+		errs = append(errs, fmt.Errorf("audio chunk duration %v exceeds the %v upload limit", c.Audio.ChunkDuration, maxChunkDuration))
+	}
+	if c.Audio.ChunkParallel < 0 {
+		errs = append(errs, fmt.Errorf("invalid audio chunk parallelism: %d", c.Audio.ChunkParallel))
+	}
+	keys := map[Key]string{}
+	names := map[string]bool{}
+	for i, p := range c.Profiles {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		} else if names[name] {
+			errs = append(errs, fmt.Errorf("profile %s: duplicate name", name))
+		}
+		names[name] = true
+		if p.Key == 0 {
+			if p.Name == "" {
+				errs = append(errs, fmt.Errorf("profile %s: a profile without a key needs a name", name))
+			}
+		} else if other, ok := keys[p.Key]; ok {
+			errs = append(errs, fmt.Errorf("profile %s: key %d is already bound to profile %s", name, p.Key, other))
+		} else if p.Key == c.RepeatKey {
+			errs = append(errs, fmt.Errorf("profile %s: key %d is already bound to repeat_key", name, p.Key))
+		}
+		if p.Key != 0 {
+			keys[p.Key] = name
+		}
+		switch p.Mode {
+		case "", "prose", "code":
+		default:
+			errs = append(errs, fmt.Errorf("profile %s: invalid mode %q: expected prose or code", name, p.Mode))
+		}
+		switch p.Output {
+		case "", "type", "paste", "clipboard":
+		default:
+			errs = append(errs, fmt.Errorf("profile %s: invalid output %q: expected type, paste or clipboard", name, p.Output))
+		}
+		if _, err := postproc.Compile(p.Rules); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", name, err))
+		}
+	}
+	if _, err := postproc.Compile(c.Rules); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Watch calls fn whenever the modification time of one of the files returned
+// by paths changes. Empty paths are ignored, and paths which are returned for
+// the first time are only recorded. It polls every interval until the context
+// is cancelled.
+func Watch(ctx context.Context, interval time.Duration, paths func() []string, fn func()) {
+	mtimes := map[string]time.Time{}
+	// poll records the modification times and reports whether a known file changed
+	poll := func() bool {
+		var changed bool
+		for _, path := range paths() {
+			if path == "" {
+				continue
+			}
+			var mtime time.Time
+			fi, err := os.Stat(path)
+			if err == nil {
+				mtime = fi.ModTime()
+			}
+			last, ok := mtimes[path]
+			switch {
+			case !ok:
+				mtimes[path] = mtime
+			case err == nil && !mtime.Equal(last):
+				mtimes[path] = mtime
+				changed = true
+			}
+		}
+		return changed
+	}
+	poll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if poll() {
+				fn()
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/icholy/whisperd/internal/secret"
+)
+
+func TestKeyCache(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	var lookups int
+	lookup := lookupKey
+	t.Cleanup(func() { lookupKey = lookup })
+	lookupKey = func(context.Context) (secret.Secret, error) {
+		lookups++
+		return "sk-keyring", nil
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	var keys KeyCache
+	resolve := func(o OpenAI) string {
+		t.Helper()
+		if err := keys.ResolveKey(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		return string(o.Key)
+	}
+	// the key file is read again on every reload, so a rotated key is used
+	for _, key := range []string{"sk-first", "sk-second"} {
+		if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if got := resolve(OpenAI{KeyFile: path, SecretService: true}); got != key {
+			t.Errorf("got %q, want %s", got, key)
+		}
+	}
+	if got := resolve(OpenAI{Key: "sk-config", SecretService: true}); got != "sk-config" {
+		t.Errorf("got %q, want sk-config", got)
+	}
+	if lookups != 0 {
+		t.Errorf("the Secret Service was asked %d times before it was needed", lookups)
+	}
+	// the Secret Service is only asked once
+	for range 2 {
+		if got := resolve(OpenAI{SecretService: true}); got != "sk-keyring" {
+			t.Errorf("got %q, want sk-keyring", got)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("the Secret Service was asked %d times, want once", lookups)
+	}
+}
+
+func TestValidateControl(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   []string
+		noSocket bool
+		noDBus   bool
+		ok       bool
+	}{
+		{name: "defaults", ok: true},
+		{name: "socket only", noDBus: true, ok: true},
+		{name: "dbus only", noSocket: true, ok: true},
+		{name: "input devices only", inputs: []string{"/dev/input/event0"}, noSocket: true, noDBus: true, ok: true},
+		{name: "nothing", noSocket: true, noDBus: true},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.OpenAI.Key = "sk-test"
+		cfg.Profiles = []Profile{{Name: "default"}}
+		cfg.Inputs = tt.inputs
+		if tt.noSocket {
+			cfg.Socket = ""
+		}
+		if tt.noDBus {
+			cfg.DBus = false
+		}
+		if err := cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.toml")
+	rules := filepath.Join(dir, "rules.toml")
+	for _, path := range []string{config, rules} {
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go Watch(ctx, time.Millisecond, func() []string { return []string{config, "", rules} }, func() {
+		changed <- struct{}{}
+	})
+	select {
+	case <-changed:
+		t.Fatal("got a change before any file was modified")
+	case <-time.After(20 * time.Millisecond):
+	}
+	for _, path := range []string{config, rules} {
+		if err := os.Chtimes(path, time.Time{}, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+			t.Fatalf("no change reported for %s", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package control
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Request is a command sent to the daemon over the control socket.
+type Request struct {
+	Command string `json:"command"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// Response is the daemon's reply to a Request.
+type Response struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Status string `json:"status,omitempty"`
+	Text   string `json:"text,omitempty"`
+}
+
+// Handler handles a single control request.
+type Handler func(req Request) Response
+
+// SocketPath returns the default control socket path.
+func SocketPath() string {
+	dir := cmp.Or(os.Getenv("XDG_RUNTIME_DIR"), os.TempDir())
+	return filepath.Join(dir, "whisperd.sock")
+}
+
+// Serve listens on the unix socket at path and dispatches newline delimited
+// JSON requests to h until the context is cancelled.
+func Serve(ctx context.Context, path string, h Handler) error {
+	// remove a stale socket left behind by a previous run, but not the socket
+	// of a daemon which is still running
+	conn, err := net.Dial("unix", path)
+	switch {
+	case err == nil:
+		conn.Close()
+		return fmt.Errorf("%s: whisperd is already running", path)
+	case errors.Is(err, syscall.ECONNREFUSED) && isSocket(path):
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go serveConn(conn, h)
+	}
+}
+
+// isSocket reports whether the file at path is a unix socket.
+func isSocket(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.Mode().Type() == os.ModeSocket
+}
+
+func serveConn(conn net.Conn, h Handler) {
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: err.Error()}
+		} else {
+			resp = h(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Send connects to the control socket at path, sends the request and returns the response.
+func Send(ctx context.Context, path string, req Request) (Response, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return Response{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("read response: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+package control
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socketPath returns a socket path which is short enough for sun_path.
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "whisperd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "whisperd.sock")
+}
+
+// serve runs Serve in the background and waits until it accepts connections.
+func serve(t *testing.T, path string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, path, func(req Request) Response {
+			return Response{OK: true, Status: req.Command}
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	for range 100 {
+		if resp, err := Send(ctx, path, Request{Command: "status"}); err == nil {
+			if !resp.OK || resp.Status != "status" {
+				t.Fatalf("got %+v", resp)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the control socket isn't accepting connections")
+}
+
+func TestServeStaleSocket(t *testing.T) {
+	path := socketPath(t)
+	// a socket file without a listener, as left behind by a crash
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	serve(t, path)
+}
+
+func TestServeAlreadyRunning(t *testing.T) {
+	path := socketPath(t)
+	serve(t, path)
+	err := Serve(context.Background(), path, func(Request) Response { return Response{} })
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("got %v, want an already running error", err)
+	}
+	// the running daemon keeps its socket
+	if _, err := Send(context.Background(), path, Request{Command: "status"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeNotASocket(t *testing.T) {
+	path := socketPath(t)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Serve(context.Background(), path, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the file was removed: %v", err)
+	}
+}
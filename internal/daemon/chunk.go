@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/vad"
+)
+
+// upload transcribes the samples. Recordings longer than ChunkDuration are
+// split at pauses, and the chunks are transcribed ChunkParallel at a time
+// and joined in order.
+func (d *Daemon) upload(ctx context.Context, cfg *Config, pcm []byte, opt audio.Options, profile *Profile) (string, error) {
+	detector := cfg.VAD
+	if detector == nil {
+		detector = &vad.Detector{SampleRate: opt.SampleRate}
+	}
+	chunks := detector.Split(pcm, cfg.ChunkDuration)
+	if len(chunks) == 1 {
+		d.Log.Info("transcribing", "profile", profile.Name)
+		return d.uploadChunk(ctx, cfg, pcm, opt, profile)
+	}
+	d.Log.Info("transcribing in chunks", "profile", profile.Name, "chunks", len(chunks), "recording", detector.Duration(len(pcm)))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	texts := make([]string, len(chunks))
+	sem := make(chan struct{}, max(cfg.ChunkParallel, 1))
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			text, err := d.uploadChunk(ctx, cfg, chunk, opt, profile)
+			if err != nil {
+				// the first error is the cause, the others are cancellations
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			d.Log.Debug("transcribed chunk", "chunk", i+1, "text", text)
+			texts[i] = strings.TrimSpace(text)
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return "", firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(slices.DeleteFunc(texts, func(s string) bool { return s == "" }), " "), nil
+}
+
+// uploadChunk encodes and transcribes a single chunk.
+func (d *Daemon) uploadChunk(ctx context.Context, cfg *Config, pcm []byte, opt audio.Options, profile *Profile) (string, error) {
+	format := cmp.Or(cfg.Format, "wav")
+	encode, ok := audio.Formats[format]
+	if !ok {
+		return "", &Error{Op: "encode", Err: fmt.Errorf("unknown audio format: %q", format)}
+	}
+	var file bytes.Buffer
+	if err := encode(&file, pcm, opt); err != nil {
+		return "", &Error{Op: "encode", Err: err}
+	}
+	d.Log.Debug("encoded audio", "format", format, "size", file.Len())
+	if cfg.Dump {
+		f, err := os.CreateTemp("", "whisperd-*."+format)
+		if err != nil {
+			return "", &Error{Op: "dump", Err: err}
+		}
+		if _, err := f.Write(file.Bytes()); err != nil {
+			f.Close()
+			return "", &Error{Op: "dump", Err: err}
+		}
+		if err := f.Close(); err != nil {
+			return "", &Error{Op: "dump", Err: err}
+		}
+		d.Log.Info("dumped", "path", f.Name())
+	}
+	text, err := cfg.Client.Transcribe(ctx, format, &file, profile.Transcribe)
+	if err != nil {
+		return "", &Error{Op: "transcribe", Err: err}
+	}
+	return text, nil
+}
@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/openai"
+	"github.com/icholy/whisperd/internal/wav"
+)
+
+type word struct {
+	text      string
+	amplitude int
+}
+
+// words are recorded as tones with these amplitudes, so that the fake API
+// can tell which chunk it was sent.
+var words = []word{
+	{"one", 4000},
+	{"two", 8000},
+	{"three", 16000},
+}
+
+// sentence returns 16kHz PCM with a 1.4s tone for every word, separated by
+// 400ms pauses.
+func sentence() []byte {
+	var pcm []byte
+	for i, w := range words {
+		if i > 0 {
+			pcm = append(pcm, make([]byte, 2*6400)...)
+		}
+		for j := range 22400 {
+			v := float64(w.amplitude) * math.Sin(2*math.Pi*300*float64(j)/16000)
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(v)))
+		}
+	}
+	return pcm
+}
+
+// chunkServer is a fake API which transcribes the words of sentence. The
+// first words take the longest, so that the chunks finish out of order.
+func chunkServer(t *testing.T, fail string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pcm, _, err := wav.Read(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the words are told apart by the peak of every 20ms
+		var text []string
+		for off := 0; off+640 <= len(pcm); off += 640 {
+			var peak int
+			for i := off; i < off+640; i += 2 {
+				peak = max(peak, int(int16(binary.LittleEndian.Uint16(pcm[i:]))))
+			}
+			for _, word := range words {
+				if peak >= word.amplitude*9/10 && peak <= word.amplitude*11/10 && !slices.Contains(text, word.text) {
+					text = append(text, word.text)
+				}
+			}
+		}
+		if len(text) == 1 {
+			i := slices.IndexFunc(words, func(w word) bool { return w.text == text[0] })
+			time.Sleep(time.Duration(len(words)-i) * 20 * time.Millisecond)
+		}
+		if len(text) == 1 && text[0] == fail {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"text":%q}`, strings.Join(text, " "))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUploadChunks(t *testing.T) {
+	opt := audio.Options{SampleRate: 16000, NumChannels: 1}
+	tests := []struct {
+		name     string
+		chunk    time.Duration
+		parallel int
+		fail     string
+		want     string
+	}{
+		{name: "whole", chunk: 0, want: "one two three"},
+		{name: "sequential", chunk: 2 * time.Second, parallel: 1, want: "one two three"},
+		{name: "parallel", chunk: 2 * time.Second, parallel: 3, want: "one two three"},
+		{name: "failed chunk", chunk: 2 * time.Second, parallel: 3, fail: "two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := chunkServer(t, tt.fail)
+			d := &Daemon{Log: slog.New(slog.DiscardHandler)}
+			cfg := &Config{
+				Client:        openai.Client{APIKey: "test", BaseURL: srv.URL},
+				ChunkDuration: tt.chunk,
+				ChunkParallel: tt.parallel,
+			}
+			text, err := d.upload(context.Background(), cfg, sentence(), opt, &Profile{Name: "test"})
+			if tt.fail != "" {
+				if err == nil {
+					t.Fatalf("got %q, want an error", text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tt.want {
+				t.Errorf("got %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
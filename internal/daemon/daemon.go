@@ -1,79 +1,821 @@
 package daemon
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/dsp"
 	"github.com/icholy/whisperd/internal/evdev"
+	"github.com/icholy/whisperd/internal/history"
+	"github.com/icholy/whisperd/internal/inputcodes"
 	"github.com/icholy/whisperd/internal/openai"
-	"github.com/icholy/whisperd/internal/pipewire"
+	"github.com/icholy/whisperd/internal/output"
+	"github.com/icholy/whisperd/internal/postproc"
 	"github.com/icholy/whisperd/internal/tray"
-	"github.com/icholy/whisperd/internal/uinput"
+	"github.com/icholy/whisperd/internal/vad"
 )
 
+// Command is an action requested from outside of the hotkey loop.
+type Command string
+
+const (
+	// Start starts recording with the named profile, or the first profile.
+	Start Command = "start"
+	// Stop stops recording and transcribes the audio.
+	Stop Command = "stop"
+	// Toggle starts recording if idle and stops it otherwise.
+	Toggle Command = "toggle"
+	// Cancel discards the recording or transcription in progress.
+	Cancel Command = "cancel"
+	// Repeat re-emits the most recent transcript.
+	Repeat Command = "repeat"
+	// Pause ignores hotkeys and start commands until Resume.
+	Pause Command = "pause"
+	// Resume ends a Pause.
+	Resume Command = "resume"
+	// Select makes the named profile active.
+	Select Command = "select"
+	// Source records from the named audio source, or the default source if empty.
+	Source Command = "source"
+	// HandsFree starts hands-free dictation with the named profile, or the
+	// active profile, and stops it if it's running.
+	HandsFree Command = "handsfree"
+)
+
+// Profile configures how recordings are transcribed and emitted.
+type Profile struct {
+	Name       string
+	Key        uint16 // hotkey, zero if the profile is only used with commands
+	Transcribe openai.TranscribeOptions
+	// Sources are the preferred audio sources, the first one that is
+	// present is used. See audio.Match.
+	Sources     []string
+	PostProcess postproc.Processor
+	Output      output.Sink
+	// HandsFree makes the hotkey start and stop hands-free dictation
+	// instead of recording while it's held.
+	HandsFree bool
+}
+
+// Config holds the daemon settings which can be replaced while it is running.
+type Config struct {
+	Inputs        []string
+	Client        openai.Client
+	Audio         audio.Backend
+	Device        string // audio source used when no other one is selected
+	Profiles      []*Profile
+	RepeatKeyCode uint16
+	Dump          bool
+	// DSP cleans up recordings before they are encoded, nil to disable.
+	DSP dsp.Filter
+	// Format is the file format recordings are uploaded in, one of
+	// audio.Formats. It defaults to wav.
+	Format string
+	// VAD trims silence and skips recordings without speech, nil to disable.
+	// It also detects the utterances of hands-free dictation.
+	VAD *vad.Detector
+	// EndSilence is the pause which ends an utterance in hands-free dictation.
+	EndSilence time.Duration
+	// HandsFreeTimeout stops hands-free dictation after this long without
+	// speech, zero to keep listening.
+	HandsFreeTimeout time.Duration
+	// ErrorTimeout is how long the Error and Offline statuses are shown
+	// before returning to Idle. Zero shows them until the next dictation.
+	ErrorTimeout time.Duration
+	// SilenceWarning is how long the microphone has to be silent while
+	// recording before the Silent status is shown. Zero disables it.
+	SilenceWarning time.Duration
+	// MaxDuration stops recordings and ends hands-free utterances which
+	// run longer, zero for no limit.
+	MaxDuration time.Duration
+	// ChunkDuration splits longer recordings into chunks which are
+	// transcribed separately, zero to upload them whole.
+	ChunkDuration time.Duration
+	// ChunkParallel is the number of chunks transcribed at once.
+	ChunkParallel int
+}
+
+// profile returns the profile with the given name, or the first profile if name is empty.
+func (c *Config) profile(name string) (*Profile, error) {
+	for _, p := range c.Profiles {
+		if name == "" || p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile: %q", name)
+}
+
+// hotkey returns the profile bound to the key code.
+func (c *Config) hotkey(code uint16) (*Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Key != 0 && p.Key == code {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Settings are the daemon options which can be changed while it is running.
+type Settings struct {
+	Paused bool
+	// Profile is the active profile. It is used by commands without a
+	// profile and by the first profile's hotkey. The first profile is
+	// active if it is empty or no longer exists.
+	Profile string
+	// Source is the audio source to record from, the default source if empty.
+	Source string
+	// HandsFree is set while hands-free dictation is running.
+	HandsFree bool
+}
+
 type Daemon struct {
+	Config
 	Log     *slog.Logger
-	Input   *os.File
-	Output  *os.File
-	Client  openai.Client
-	KeyCode uint16
-	Dump    bool
+	History history.History
+
+	once     sync.Once
+	commands chan command
+	reloads  chan reload
+
+	mu        sync.Mutex
+	status    tray.Status
+	settings  Settings
+	listeners []func(Event)
+}
+
+// EventKind identifies the type of an Event.
+type EventKind int
+
+const (
+	// StatusChanged is sent when the daemon status changes.
+	StatusChanged EventKind = iota
+	// Transcribed is sent after a transcript is emitted.
+	Transcribed
+	// Failed is sent when recording, transcribing or emitting fails.
+	Failed
+	// SettingsChanged is sent when the daemon settings change.
+	SettingsChanged
+)
+
+// Event describes something that happened in the daemon.
+type Event struct {
+	Kind    EventKind
+	Status  tray.Status
+	Profile string
+	Text    string
+	Err     error
+}
+
+// Error is a failure while handling a dictation. The daemon keeps running
+// after these errors and reports them with a Failed event.
+type Error struct {
+	Op  string // record, dump, transcribe, post-process or emit
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// fail reports a dictation error and shows the Error status, or Offline if
+// the API couldn't be reached.
+func (d *Daemon) fail(st *state, err error) {
+	d.Log.Error("dictation failed", "error", err)
+	status := tray.Error
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		status = tray.Offline
+	}
+	d.setStatus(status, err.Error())
+	if d.ErrorTimeout > 0 {
+		st.clear = time.After(d.ErrorTimeout)
+	}
+	d.publish(Event{Kind: Failed, Err: err})
+}
+
+// Listen registers fn to be called for every event.
+// It is called from the daemon's goroutines and must not block.
+func (d *Daemon) Listen(fn func(Event)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listeners = append(d.listeners, fn)
+}
+
+func (d *Daemon) publish(e Event) {
+	d.mu.Lock()
+	listeners := d.listeners
+	d.mu.Unlock()
+	for _, fn := range listeners {
+		fn(e)
+	}
+}
+
+type command struct {
+	name Command
+	arg  string
+	errc chan error
+}
+
+type reload struct {
+	config Config
+	errc   chan error
+}
+
+func (d *Daemon) init() {
+	d.once.Do(func() {
+		d.commands = make(chan command)
+		d.reloads = make(chan reload)
+	})
+}
+
+// Reload replaces the daemon configuration. Input devices are reopened if
+// they changed and a recording in progress is finished with its original profile.
+func (d *Daemon) Reload(ctx context.Context, cfg Config) error {
+	d.init()
+	r := reload{config: cfg, errc: make(chan error, 1)}
+	select {
+	case d.reloads <- r:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-r.errc
+}
+
+// Send sends a command to the daemon and waits for it to be handled.
+// The argument is the profile name for Start, Toggle, Select and HandsFree,
+// and the source name for Source. Other commands ignore it.
+func (d *Daemon) Send(ctx context.Context, cmd Command, arg string) error {
+	switch cmd {
+	case Start, Stop, Toggle, Cancel, Repeat, Pause, Resume, Select, Source, HandsFree:
+	default:
+		return fmt.Errorf("unknown command: %q", cmd)
+	}
+	d.init()
+	c := command{name: cmd, arg: arg, errc: make(chan error, 1)}
+	select {
+	case d.commands <- c:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-c.errc
+}
+
+// Status returns the current daemon status.
+func (d *Daemon) Status() tray.Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// Settings returns the current daemon settings.
+func (d *Daemon) Settings() Settings {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.settings
+}
+
+// updateSettings applies fn to the settings and publishes the change.
+func (d *Daemon) updateSettings(fn func(s *Settings)) {
+	d.mu.Lock()
+	fn(&d.settings)
+	d.mu.Unlock()
+	d.publish(Event{Kind: SettingsChanged})
+}
+
+// active returns the active profile.
+func (d *Daemon) active() *Profile {
+	if p, err := d.profile(d.Settings().Profile); err == nil {
+		return p
+	}
+	return d.Profiles[0]
+}
+
+// idle shows the Idle status, Paused while dictation is paused, or
+// Offline while the input devices are lost.
+func (d *Daemon) idle(st *state) {
+	if st.inputErr != nil {
+		d.setStatus(tray.Offline, st.inputErr.Error())
+		return
+	}
+	if d.Settings().Paused {
+		d.setStatus(tray.Paused, "")
+	} else {
+		d.setStatus(tray.Idle, "")
+	}
 }
 
+// setStatus updates the status, the detail is shown in the tray tooltip.
+func (d *Daemon) setStatus(s tray.Status, detail string) {
+	d.mu.Lock()
+	changed := d.status != s
+	d.status = s
+	d.mu.Unlock()
+	tray.SetStatus(s, detail)
+	if changed {
+		d.publish(Event{Kind: StatusChanged, Status: s})
+	}
+}
+
+// state is owned by the Run loop.
+type state struct {
+	rec     audio.Recorder
+	recKey  uint16
+	profile *Profile
+	// cancel cancels the transcription in progress, it is nil when idle
+	cancel context.CancelFunc
+	// clear fires when the Error or Offline status should be cleared
+	clear <-chan time.Time
+	// inputErr is the error which caused the input devices to be lost,
+	// they are reopened when reopen fires
+	inputErr error
+	reopen   <-chan time.Time
+	// limit fires when the recording reaches the maximum duration
+	limit <-chan time.Time
+	// hands is the hands-free dictation in progress
+	hands *handsFree
+}
+
+// reopenInterval is how often lost input devices are reopened.
+const reopenInterval = 5 * time.Second
+
 func (d *Daemon) Run(ctx context.Context) error {
+	d.init()
+	keys := make(chan inputcodes.Event)
+	errc := make(chan error, 1)
+	closeInputs, err := d.openInputs(d.Inputs, keys, errc)
+	if err != nil {
+		return err
+	}
+	defer func() { closeInputs() }()
+	done := make(chan error, 1)
+	handsDone := make(chan error, 1)
+	var st state
+	d.idle(&st)
+	d.Log.Info("waiting for key down")
 	for {
-		tray.SetStatus(tray.Idle)
-		d.Log.Info("waiting for key down")
-		if err := evdev.WaitForKey(d.Input, d.KeyCode, 1); err != nil {
-			return fmt.Errorf("wait for key down: %w", err)
-		}
-		tray.SetStatus(tray.Recording)
-		d.Log.Info("starting recording")
-		rec, err := pipewire.Record(ctx, pipewire.Options{
-			SampleRate:  16000,
-			NumChannels: 1,
-		})
-		if err != nil {
-			return fmt.Errorf("start recording: %w", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errc:
+			// the devices are reopened until they come back
+			closeInputs()
+			closeInputs = func() {}
+			// the key up of a hotkey recording will never arrive
+			if st.rec != nil && st.recKey != 0 {
+				st.rec.Stop()
+				st.rec = nil
+			}
+			d.Log.Error("input device lost", "error", err)
+			st.inputErr = err
+			st.reopen = time.After(reopenInterval)
+			st.clear = nil
+			d.idle(&st)
+			d.publish(Event{Kind: Failed, Err: &Error{Op: "input", Err: err}})
+		case <-st.reopen:
+			closeNew, err := d.openInputs(d.Inputs, keys, errc)
+			if err != nil {
+				d.Log.Debug("input devices unavailable", "error", err)
+				st.reopen = time.After(reopenInterval)
+				continue
+			}
+			closeInputs = closeNew
+			st.inputErr = nil
+			st.reopen = nil
+			d.Log.Info("input devices reopened")
+			if d.Status() == tray.Offline {
+				d.idle(&st)
+			}
+		case <-st.clear:
+			st.clear = nil
+			if s := d.Status(); s == tray.Error || s == tray.Offline {
+				d.idle(&st)
+			}
+		case err := <-done:
+			st.cancel()
+			st.cancel = nil
+			switch {
+			case errors.Is(err, context.Canceled):
+				d.Log.Info("transcription cancelled")
+				d.idle(&st)
+			case errors.Is(err, errNoSpeech):
+				d.Log.Info("no speech detected, skipping transcription")
+				d.idle(&st)
+			case err != nil:
+				d.fail(&st, err)
+			default:
+				d.idle(&st)
+			}
+			d.Log.Info("waiting for key down")
+		case <-st.limit:
+			st.limit = nil
+			if st.rec != nil {
+				d.Log.Warn("maximum recording duration reached", "duration", d.MaxDuration)
+				if err := d.stop(ctx, &st, done); err != nil {
+					d.fail(&st, err)
+				}
+			}
+		case err := <-handsDone:
+			d.endHandsFree(&st, err)
+		case r := <-d.reloads:
+			if !slices.Equal(r.config.Inputs, d.Inputs) || st.inputErr != nil {
+				closeNew, err := d.openInputs(r.config.Inputs, keys, errc)
+				if err != nil {
+					r.errc <- err
+					continue
+				}
+				closeInputs()
+				closeInputs = closeNew
+				if st.inputErr != nil {
+					st.inputErr = nil
+					st.reopen = nil
+					d.idle(&st)
+				}
+			}
+			// backends like audio.Warm hold on to the microphone
+			if c, ok := d.Audio.(io.Closer); ok {
+				c.Close()
+			}
+			d.Config = r.config
+			d.Log.Info("reloaded configuration")
+			r.errc <- nil
+		case cmd := <-d.commands:
+			err := d.handle(ctx, &st, cmd, done, handsDone)
+			var derr *Error
+			if errors.As(err, &derr) {
+				d.fail(&st, err)
+			}
+			cmd.errc <- err
+		case e := <-keys:
+			bound, isHotkey := d.hotkey(e.Code)
+			profile := bound
+			if isHotkey && bound == d.Profiles[0] {
+				profile = d.active()
+			}
+			switch {
+			case isHotkey && e.Value == 1 && st.hands != nil:
+				if bound.HandsFree {
+					st.hands.finish()
+				} else {
+					d.Log.Info("ignoring hotkey during hands-free dictation")
+				}
+			case isHotkey && e.Value == 1 && bound.HandsFree && st.rec == nil:
+				if err := d.handsFreeAllowed(&st); err != nil {
+					d.Log.Info("ignoring hotkey", "reason", err)
+					continue
+				}
+				if err := d.startHandsFree(ctx, &st, profile, handsDone); err != nil {
+					d.fail(&st, err)
+				}
+			case isHotkey && e.Value == 1 && st.rec == nil:
+				if d.Settings().Paused {
+					d.Log.Info("ignoring hotkey while paused")
+					continue
+				}
+				if st.cancel != nil {
+					d.Log.Info("ignoring hotkey while transcribing")
+					continue
+				}
+				if err := d.start(ctx, &st, profile); err != nil {
+					d.fail(&st, err)
+					continue
+				}
+				st.recKey = e.Code
+				d.Log.Info("waiting for key up")
+			case e.Code == st.recKey && e.Value == 0 && st.rec != nil:
+				if err := d.stop(ctx, &st, done); err != nil {
+					d.fail(&st, err)
+				}
+			case e.Code == d.RepeatKeyCode && e.Value == 1 && d.RepeatKeyCode != 0:
+				err := d.repeat(ctx, &st)
+				var derr *Error
+				if errors.As(err, &derr) {
+					d.fail(&st, err)
+				} else if err != nil {
+					// a rejected repeat doesn't put the tray in the error state
+					d.Log.Info("ignoring repeat key", "error", err)
+				}
+			}
 		}
-		d.Log.Info("waiting for key up")
-		if err := evdev.WaitForKey(d.Input, d.KeyCode, 0); err != nil {
-			return fmt.Errorf("wait for key up: %w", err)
+	}
+}
+
+// handle runs a command sent with Send.
+func (d *Daemon) handle(ctx context.Context, st *state, cmd command, done, handsDone chan<- error) error {
+	switch cmd.name {
+	case Toggle:
+		if st.hands != nil {
+			st.hands.finish()
+			return nil
+		}
+		if st.rec != nil {
+			return d.stop(ctx, st, done)
+		}
+		fallthrough
+	case Start:
+		if st.hands != nil {
+			return errors.New("hands-free dictation in progress")
+		}
+		if st.rec != nil {
+			return errors.New("already recording")
 		}
-		d.Log.Info("stopping recording")
-		if err := rec.Stop(); err != nil {
-			return fmt.Errorf("stop recording: %w", err)
+		if st.cancel != nil {
+			return errors.New("transcription in progress")
 		}
-		var wav bytes.Buffer
-		if err := rec.WriteWAV(&wav); err != nil {
-			return fmt.Errorf("write wav: %w", err)
+		if d.Settings().Paused {
+			return errors.New("paused")
 		}
-		if d.Dump {
-			f, err := os.CreateTemp("", "whisperd-*.wav")
+		profile := d.active()
+		if cmd.arg != "" {
+			var err error
+			profile, err = d.profile(cmd.arg)
 			if err != nil {
-				return fmt.Errorf("dump wav: %w", err)
+				return err
 			}
-			if _, err := wav.WriteTo(f); err != nil {
-				f.Close()
-				return fmt.Errorf("dump wav: %w", err)
+		}
+		st.recKey = 0
+		return d.start(ctx, st, profile)
+	case Stop:
+		if st.hands != nil {
+			st.hands.finish()
+			return nil
+		}
+		if st.rec == nil {
+			return errors.New("not recording")
+		}
+		return d.stop(ctx, st, done)
+	case Cancel:
+		switch {
+		case st.hands != nil:
+			st.hands.cancel()
+			return nil
+		case st.rec != nil:
+			d.Log.Info("cancelling recording")
+			err := st.rec.Stop()
+			st.rec = nil
+			d.idle(st)
+			if err != nil {
+				return &Error{Op: "record", Err: err}
 			}
-			if err := f.Close(); err != nil {
-				return fmt.Errorf("dump wav: %w", err)
+			return nil
+		case st.cancel != nil:
+			st.cancel()
+			return nil
+		default:
+			return errors.New("nothing to cancel")
+		}
+	case Repeat:
+		return d.repeat(ctx, st)
+	case Pause, Resume:
+		paused := cmd.name == Pause
+		if paused {
+			d.Log.Info("pausing dictation")
+		} else {
+			d.Log.Info("resuming dictation")
+		}
+		d.updateSettings(func(s *Settings) { s.Paused = paused })
+		if paused && st.hands != nil {
+			st.hands.finish()
+		}
+		if s := d.Status(); s == tray.Idle || s == tray.Paused {
+			d.idle(st)
+		}
+	case Select:
+		if _, err := d.profile(cmd.arg); err != nil {
+			return err
+		}
+		d.Log.Info("selecting profile", "profile", cmd.arg)
+		d.updateSettings(func(s *Settings) { s.Profile = cmd.arg })
+	case Source:
+		d.Log.Info("selecting source", "source", cmd.arg)
+		d.updateSettings(func(s *Settings) { s.Source = cmd.arg })
+	case HandsFree:
+		if st.hands != nil {
+			st.hands.finish()
+			return nil
+		}
+		if err := d.handsFreeAllowed(st); err != nil {
+			return err
+		}
+		profile := d.active()
+		if cmd.arg != "" {
+			var err error
+			profile, err = d.profile(cmd.arg)
+			if err != nil {
+				return err
 			}
-			d.Log.Info("dumped", "path", f.Name())
 		}
-		tray.SetStatus(tray.Transcribing)
-		d.Log.Info("transcribing")
-		text, err := d.Client.Transcribe(ctx, &wav)
+		return d.startHandsFree(ctx, st, profile, handsDone)
+	}
+	return nil
+}
+
+// start begins recording for the profile.
+func (d *Daemon) start(ctx context.Context, st *state, profile *Profile) error {
+	d.setStatus(tray.Recording, "")
+	target := d.source(ctx, profile)
+	d.Log.Info("starting recording", "profile", profile.Name, "source", target)
+	rec, err := d.Audio.Record(ctx, audio.Options{
+		SampleRate:  16000,
+		NumChannels: 1,
+		Target:      target,
+		Level:       d.meter(d.SilenceWarning),
+	})
+	if err != nil {
+		return &Error{Op: "record", Err: err}
+	}
+	st.rec = rec
+	st.profile = profile
+	st.limit = nil
+	if d.MaxDuration > 0 {
+		st.limit = time.After(d.MaxDuration)
+	}
+	return nil
+}
+
+// source returns the audio source to record from. The source selected with
+// the Source command takes precedence over the profile's preferred sources,
+// which take precedence over the configured device.
+func (d *Daemon) source(ctx context.Context, profile *Profile) string {
+	if s := d.Settings().Source; s != "" {
+		return s
+	}
+	if len(profile.Sources) > 0 {
+		sources, err := audio.ListSources(ctx, d.Audio)
+		if err != nil {
+			d.Log.Warn("failed to list audio sources", "error", err)
+		} else if s, ok := audio.Match(sources, profile.Sources); ok {
+			return s.Name
+		}
+	}
+	return d.Device
+}
+
+// silenceThreshold is the level in dBFS below which the input is silent.
+const silenceThreshold = -60
+
+// meter returns a level callback which shows the input level in the tray
+// and switches between the Recording and Silent statuses.
+func (d *Daemon) meter(warning time.Duration) func(audio.Level) {
+	lastSound := time.Now()
+	return func(l audio.Level) {
+		db := l.DB()
+		// map -60..0 dBFS to the meter range
+		tray.SetLevel((db - silenceThreshold) / -silenceThreshold)
+		now := time.Now()
+		if db > silenceThreshold {
+			lastSound = now
+		}
+		silent := warning > 0 && now.Sub(lastSound) >= warning
+		switch s := d.Status(); {
+		case silent && s == tray.Recording:
+			d.Log.Warn("no sound from the microphone", "duration", warning)
+			d.setStatus(tray.Silent, "")
+		case !silent && s == tray.Silent:
+			d.setStatus(tray.Recording, "")
+		}
+	}
+}
+
+// stop ends the recording and starts transcribing it in the background.
+// The result is sent on done.
+func (d *Daemon) stop(ctx context.Context, st *state, done chan<- error) error {
+	d.Log.Info("stopping recording")
+	rec := st.rec
+	st.rec = nil
+	if err := rec.Stop(); err != nil {
+		return &Error{Op: "record", Err: err}
+	}
+	tctx, cancel := context.WithCancel(ctx)
+	st.cancel = cancel
+	d.setStatus(tray.Transcribing, "")
+	cfg, profile := d.Config, st.profile
+	go func() {
+		done <- d.transcribe(tctx, &cfg, rec, profile)
+	}()
+	return nil
+}
+
+// openInputs opens the input devices and starts forwarding their key events.
+// The returned function closes the devices.
+func (d *Daemon) openInputs(paths []string, keys chan<- inputcodes.Event, errc chan<- error) (func(), error) {
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
 		if err != nil {
-			return fmt.Errorf("transcribe: %w", err)
+			closeAll()
+			return nil, fmt.Errorf("open input device: %w", err)
+		}
+		files = append(files, f)
+	}
+	for _, f := range files {
+		go func() {
+			err := evdev.ReadKeys(f, keys)
+			if errors.Is(err, os.ErrClosed) {
+				return
+			}
+			select {
+			case errc <- fmt.Errorf("%s: %w", f.Name(), err):
+			default:
+			}
+		}()
+	}
+	return closeAll, nil
+}
+
+// errNoSpeech is returned by transcribe when the VAD found no speech.
+var errNoSpeech = errors.New("no speech detected")
+
+// transcribe transcribes the stopped recording and emits the resulting text
+// as configured by the profile.
+func (d *Daemon) transcribe(ctx context.Context, cfg *Config, rec audio.Recorder, profile *Profile) error {
+	pcm := rec.PCM()
+	if cfg.VAD != nil {
+		trimmed, speech := cfg.VAD.Trim(pcm)
+		d.Log.Info("detected speech", "speech", speech, "recording", cfg.VAD.Duration(len(pcm)))
+		if trimmed == nil {
+			return errNoSpeech
 		}
-		d.Log.Info("emitting", "text", text)
-		if err := uinput.EmitText(d.Output, text); err != nil {
-			return fmt.Errorf("emit text: %w", err)
+		pcm = trimmed
+	}
+	return d.dictate(ctx, cfg, pcm, rec.Options(), profile)
+}
+
+// dictate transcribes the samples and emits the resulting text as
+// configured by the profile.
+func (d *Daemon) dictate(ctx context.Context, cfg *Config, pcm []byte, opt audio.Options, profile *Profile) error {
+	if cfg.DSP != nil {
+		pcm = dsp.Process(cfg.DSP, pcm, opt.SampleRate)
+	}
+	text, err := d.upload(ctx, cfg, pcm, opt, profile)
+	if err != nil {
+		return err
+	}
+	if profile.PostProcess != nil {
+		text, err = profile.PostProcess.Process(ctx, text)
+		if err != nil {
+			return &Error{Op: "post-process", Err: err}
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.History.Add(text)
+	d.Log.Info("emitting", "text", text)
+	d.setStatus(tray.Typing, "")
+	if err := profile.Output.Emit(ctx, text); err != nil {
+		return &Error{Op: "emit", Err: err}
+	}
+	// processors like postproc.Spacing continue from the emitted text
+	if c, ok := profile.PostProcess.(postproc.Committer); ok {
+		c.Commit(text)
+	}
+	d.publish(Event{Kind: Transcribed, Profile: profile.Name, Text: text})
+	return nil
+}
+
+// errBusy is returned when repeating while a transcript may be typed.
+var errBusy = errors.New("busy: a transcription is in progress")
+
+// repeat re-emits the most recent transcript through the output of the
+// active profile, without calling the API.
+func (d *Daemon) repeat(ctx context.Context, st *state) error {
+	last, ok := d.History.Last()
+	if !ok {
+		d.Log.Info("no transcript to repeat")
+		return nil
+	}
+	// the transcript would be typed into the middle of the one in progress
+	if st.cancel != nil || st.hands != nil {
+		return errBusy
+	}
+	d.Log.Info("repeating", "text", last.Text)
+	recording := st.rec != nil
+	if !recording {
+		d.setStatus(tray.Typing, "")
+	}
+	if err := d.active().Output.Emit(ctx, last.Text); err != nil {
+		return &Error{Op: "emit", Err: err}
+	}
+	if !recording {
+		d.idle(st)
+	}
+	return nil
 }
@@ -0,0 +1,325 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/openai"
+	"github.com/icholy/whisperd/internal/tray"
+)
+
+// sink records the emitted transcripts.
+type sink chan string
+
+func (s sink) Emit(ctx context.Context, text string) error {
+	s <- text
+	return nil
+}
+
+// testDaemon is a daemon recording from audio.Fake and transcribing with a
+// fake API which replies with the text once hold is closed.
+type testDaemon struct {
+	*Daemon
+	status  chan tray.Status
+	emitted sink
+	server  *httptest.Server
+
+	mu   sync.Mutex
+	hold chan struct{}
+}
+
+// newTestDaemon runs a daemon with a single profile named "default".
+func newTestDaemon(t *testing.T, text string) *testDaemon {
+	t.Helper()
+	tray.Enabled = false
+	td := &testDaemon{
+		status:  make(chan tray.Status, 100),
+		emitted: make(sink, 10),
+		hold:    make(chan struct{}),
+	}
+	close(td.hold)
+	td.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		td.mu.Lock()
+		hold := td.hold
+		td.mu.Unlock()
+		select {
+		case <-hold:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprintf(w, `{"text":%q}`, text)
+	}))
+	t.Cleanup(td.server.Close)
+	td.Daemon = &Daemon{
+		Config: Config{
+			Client:   openai.Client{APIKey: "test", BaseURL: td.server.URL},
+			Audio:    audio.Fake{PCM: make([]byte, 3200)},
+			Profiles: []*Profile{{Name: "default", Output: td.emitted}},
+		},
+		Log: slog.New(slog.DiscardHandler),
+	}
+	td.Listen(func(e Event) {
+		if e.Kind == StatusChanged {
+			td.status <- e.Status
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- td.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-errc
+	})
+	return td
+}
+
+// block makes the fake API wait until the returned function is called.
+// It must be called before the daemon starts transcribing.
+func (td *testDaemon) block() func() {
+	hold := make(chan struct{})
+	td.mu.Lock()
+	td.hold = hold
+	td.mu.Unlock()
+	return func() { close(hold) }
+}
+
+func (td *testDaemon) send(t *testing.T, cmd Command, arg string) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return td.Send(ctx, cmd, arg)
+}
+
+// waitStatus waits for the daemon to change to the status.
+func (td *testDaemon) waitStatus(t *testing.T, want tray.Status) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-td.status:
+			if s == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for status %v, status is %v", want, td.Status())
+		}
+	}
+}
+
+// waitEmitted waits for a transcript to be emitted.
+func (td *testDaemon) waitEmitted(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-td.emitted:
+		if got != want {
+			t.Fatalf("emitted %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	td := newTestDaemon(t, "hello")
+	if err := td.send(t, Stop, ""); err == nil || err.Error() != "not recording" {
+		t.Fatalf("stop while idle: got %v, want not recording", err)
+	}
+	if err := td.send(t, Start, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Recording)
+	if err := td.send(t, Start, ""); err == nil || err.Error() != "already recording" {
+		t.Fatalf("start while recording: got %v, want already recording", err)
+	}
+	if err := td.send(t, Stop, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Transcribing)
+	td.waitEmitted(t, "hello")
+	td.waitStatus(t, tray.Idle)
+	if last, _ := td.History.Last(); last.Text != "hello" {
+		t.Errorf("history has %q, want hello", last.Text)
+	}
+}
+
+func TestToggle(t *testing.T) {
+	td := newTestDaemon(t, "toggled")
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Recording)
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitEmitted(t, "toggled")
+	td.waitStatus(t, tray.Idle)
+	if err := td.send(t, Toggle, "nope"); err == nil {
+		t.Error("toggle with an unknown profile: expected an error")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	td := newTestDaemon(t, "hello")
+	if err := td.send(t, Cancel, ""); err == nil || err.Error() != "nothing to cancel" {
+		t.Fatalf("cancel while idle: got %v, want nothing to cancel", err)
+	}
+	// cancelling a recording discards it
+	if err := td.send(t, Start, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Recording)
+	if err := td.send(t, Cancel, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Idle)
+	// cancelling a transcription aborts the request
+	release := td.block()
+	defer release()
+	if err := td.send(t, Start, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Stop, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Transcribing)
+	if err := td.send(t, Cancel, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Idle)
+	select {
+	case text := <-td.emitted:
+		t.Errorf("emitted %q after cancel", text)
+	default:
+	}
+}
+
+func TestTranscriptionInProgress(t *testing.T) {
+	td := newTestDaemon(t, "hello")
+	// a previous transcript to repeat
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitEmitted(t, "hello")
+	td.waitStatus(t, tray.Idle)
+	release := td.block()
+	if err := td.send(t, Start, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Stop, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Transcribing)
+	for _, cmd := range []Command{Start, Toggle} {
+		if err := td.send(t, cmd, ""); err == nil || err.Error() != "transcription in progress" {
+			t.Errorf("%s while transcribing: got %v, want transcription in progress", cmd, err)
+		}
+	}
+	if err := td.send(t, Repeat, ""); !errors.Is(err, errBusy) {
+		t.Errorf("repeat while transcribing: got %v, want %v", err, errBusy)
+	}
+	release()
+	td.waitEmitted(t, "hello")
+	td.waitStatus(t, tray.Idle)
+	if err := td.send(t, Repeat, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitEmitted(t, "hello")
+}
+
+func TestReload(t *testing.T) {
+	td := newTestDaemon(t, "hello")
+	other := make(sink, 10)
+	cfg := td.Config
+	cfg.Profiles = []*Profile{{Name: "other", Output: other}}
+	// a recording in progress is finished with its original profile
+	if err := td.send(t, Start, "default"); err != nil {
+		t.Fatal(err)
+	}
+	td.waitStatus(t, tray.Recording)
+	if err := td.Reload(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Stop, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitEmitted(t, "hello")
+	td.waitStatus(t, tray.Idle)
+	// new recordings use the new profiles
+	if err := td.send(t, Start, "default"); err == nil {
+		t.Error("start with a removed profile: expected an error")
+	}
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-other:
+		if got != "hello" {
+			t.Errorf("emitted %q, want hello", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the new profile's output")
+	}
+	// reloading fails without changing anything if the inputs can't be opened
+	bad := cfg
+	bad.Inputs = []string{"/nonexistent/input"}
+	if err := td.Reload(context.Background(), bad); err == nil {
+		t.Error("reload with a missing input device: expected an error")
+	}
+	if len(td.Daemon.Config.Inputs) != 0 {
+		t.Errorf("inputs changed to %v after a failed reload", td.Daemon.Config.Inputs)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	td := newTestDaemon(t, "hello")
+	if err := td.send(t, Repeat, ""); err != nil {
+		t.Fatalf("repeat without a transcript: %v", err)
+	}
+	other := make(sink, 10)
+	cfg := td.Config
+	cfg.Profiles = append(cfg.Profiles, &Profile{Name: "other", Output: other})
+	if err := td.Reload(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Toggle, ""); err != nil {
+		t.Fatal(err)
+	}
+	td.waitEmitted(t, "hello")
+	td.waitStatus(t, tray.Idle)
+	// the transcript is typed through the active profile's output
+	if err := td.send(t, Select, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.send(t, Repeat, ""); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-other:
+		if got != "hello" {
+			t.Errorf("repeated %q, want hello", got)
+		}
+	case got := <-td.emitted:
+		t.Errorf("repeated %q through the profile which produced it", got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the repeat")
+	}
+}
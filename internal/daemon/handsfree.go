@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/tray"
+	"github.com/icholy/whisperd/internal/vad"
+)
+
+// handsFree is a hands-free dictation in progress. It listens continuously,
+// splits the audio into utterances at pauses, and transcribes them in order.
+type handsFree struct {
+	profile *Profile
+	cancel  context.CancelFunc
+	stop    chan struct{}
+}
+
+// finish stops listening, the utterances already heard are still transcribed.
+func (h *handsFree) finish() {
+	select {
+	case <-h.stop:
+	default:
+		close(h.stop)
+	}
+}
+
+// handsFreeAllowed returns an error if hands-free dictation can't start.
+func (d *Daemon) handsFreeAllowed(st *state) error {
+	switch {
+	case st.rec != nil:
+		return errors.New("already recording")
+	case st.cancel != nil:
+		return errors.New("transcription in progress")
+	case d.Settings().Paused:
+		return errors.New("paused")
+	}
+	return nil
+}
+
+// startHandsFree starts hands-free dictation with the profile. The result is sent on done.
+func (d *Daemon) startHandsFree(ctx context.Context, st *state, profile *Profile, done chan<- error) error {
+	target := d.source(ctx, profile)
+	d.Log.Info("starting hands-free dictation", "profile", profile.Name, "source", target)
+	rec, err := d.Audio.Record(ctx, audio.Options{
+		SampleRate:  16000,
+		NumChannels: 1,
+		Target:      target,
+		Level:       d.meter(0),
+	})
+	if err != nil {
+		return &Error{Op: "record", Err: err}
+	}
+	seg := &vad.Segmenter{EndSilence: d.EndSilence, MaxLength: d.MaxDuration}
+	if d.VAD != nil {
+		seg.Detector = *d.VAD
+	}
+	hctx, cancel := context.WithCancel(ctx)
+	h := &handsFree{profile: profile, cancel: cancel, stop: make(chan struct{})}
+	st.hands = h
+	d.setStatus(tray.Listening, "")
+	d.updateSettings(func(s *Settings) { s.HandsFree = true })
+	cfg := d.Config
+	go func() {
+		done <- d.listen(hctx, &cfg, h, rec, seg)
+	}()
+	return nil
+}
+
+// endHandsFree cleans up after the hands-free dictation returned err.
+func (d *Daemon) endHandsFree(st *state, err error) {
+	h := st.hands
+	h.cancel()
+	st.hands = nil
+	d.updateSettings(func(s *Settings) { s.HandsFree = false })
+	switch {
+	case errors.Is(err, context.Canceled):
+		d.Log.Info("hands-free dictation cancelled")
+		d.idle(st)
+	case err != nil:
+		d.fail(st, err)
+	default:
+		d.Log.Info("hands-free dictation stopped")
+		d.idle(st)
+	}
+}
+
+// listen runs the hands-free dictation until it's finished, cancelled,
+// the timeout passes without speech, or an utterance fails.
+func (d *Daemon) listen(ctx context.Context, cfg *Config, h *handsFree, rec audio.Recorder, seg *vad.Segmenter) error {
+	timeout := cfg.HandsFreeTimeout
+	utterances := make(chan []byte, 8)
+	timedOut := make(chan struct{})
+	go func() {
+		defer close(utterances)
+		stream := rec.Stream()
+		buf := make([]byte, 4096)
+		quiet := false
+		for {
+			n, err := stream.Read(buf)
+			for _, u := range seg.Write(buf[:n]) {
+				utterances <- u
+			}
+			if timeout > 0 && !quiet && seg.Silence() >= timeout {
+				close(timedOut)
+				quiet = true
+			}
+			if errors.Is(err, audio.ErrOverrun) {
+				d.Log.Warn("hands-free dictation fell behind, audio was dropped")
+				continue
+			}
+			if err != nil {
+				break
+			}
+		}
+		if u := seg.Flush(); u != nil {
+			utterances <- u
+		}
+	}()
+	stopped := false
+	stopRecording := func() error {
+		if stopped {
+			return nil
+		}
+		stopped = true
+		return rec.Stop()
+	}
+	defer func() {
+		stopRecording()
+		// unblock the segmenter if it's waiting to send an utterance
+		go func() {
+			for range utterances {
+			}
+		}()
+	}()
+	stop, timeoutc := h.stop, timedOut
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stop:
+			stop = nil
+			if err := stopRecording(); err != nil {
+				return &Error{Op: "record", Err: err}
+			}
+		case <-timeoutc:
+			timeoutc = nil
+			d.Log.Info("no speech, stopping hands-free dictation", "timeout", timeout)
+			if err := stopRecording(); err != nil {
+				return &Error{Op: "record", Err: err}
+			}
+		case u, ok := <-utterances:
+			if !ok {
+				return nil
+			}
+			d.setStatus(tray.Transcribing, "")
+			if err := d.dictate(ctx, cfg, u, rec.Options(), h.profile); err != nil {
+				return err
+			}
+			if !stopped {
+				d.setStatus(tray.Listening, "")
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package dbusapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/icholy/whisperd/internal/daemon"
+)
+
+const (
+	// Name is the well-known bus name owned by whisperd.
+	Name = "org.whisperd.Daemon"
+	// Path is the object path of the daemon object.
+	Path = dbus.ObjectPath("/org/whisperd/Daemon")
+	// Interface is the name of the daemon interface.
+	Interface = "org.whisperd.Daemon"
+)
+
+// object implements the org.whisperd.Daemon methods.
+type object struct {
+	ctx context.Context
+	d   *daemon.Daemon
+}
+
+func (o *object) send(cmd daemon.Command, profile string) *dbus.Error {
+	if err := o.d.Send(o.ctx, cmd, profile); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (o *object) StartRecording(profile string) *dbus.Error {
+	return o.send(daemon.Start, profile)
+}
+
+func (o *object) StopRecording() *dbus.Error {
+	return o.send(daemon.Stop, "")
+}
+
+func (o *object) Toggle(profile string) *dbus.Error {
+	return o.send(daemon.Toggle, profile)
+}
+
+func (o *object) Cancel() *dbus.Error {
+	return o.send(daemon.Cancel, "")
+}
+
+func (o *object) Repeat() *dbus.Error {
+	return o.send(daemon.Repeat, "")
+}
+
+func (o *object) LastTranscript() (string, *dbus.Error) {
+	last, ok := o.d.History.Last()
+	if !ok {
+		return "", dbus.MakeFailedError(fmt.Errorf("no transcript"))
+	}
+	return last.Text, nil
+}
+
+// Serve exports the daemon on the session bus until the context is cancelled.
+//
+// The object has the methods StartRecording(profile), StopRecording(), Toggle(profile),
+// Cancel(), Repeat() and LastTranscript(), the read-only Status property, and emits the
+// StateChanged(status) and Transcribed(profile, text) signals.
+func Serve(ctx context.Context, d *daemon.Daemon) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	obj := &object{ctx: ctx, d: d}
+	if err := conn.Export(obj, Path, Interface); err != nil {
+		return err
+	}
+	props, err := prop.Export(conn, Path, prop.Map{
+		Interface: {
+			"Status": {
+				Value:    d.Status().String(),
+				Writable: false,
+				Emit:     prop.EmitTrue,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       Interface,
+				Methods:    introspect.Methods(obj),
+				Properties: props.Introspection(Interface),
+				Signals: []introspect.Signal{
+					{
+						Name: "StateChanged",
+						Args: []introspect.Arg{{Name: "status", Type: "s"}},
+					},
+					{
+						Name: "Transcribed",
+						Args: []introspect.Arg{
+							{Name: "profile", Type: "s"},
+							{Name: "text", Type: "s"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return err
+	}
+	reply, err := conn.RequestName(Name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("bus name %s is already taken", Name)
+	}
+	d.Listen(func(e daemon.Event) {
+		switch e.Kind {
+		case daemon.StatusChanged:
+			props.SetMust(Interface, "Status", e.Status.String())
+			conn.Emit(Path, Interface+".StateChanged", e.Status.String())
+		case daemon.Transcribed:
+			conn.Emit(Path, Interface+".Transcribed", e.Profile, e.Text)
+		}
+	})
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,59 @@
+package dsp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Filter transforms mono samples in the range -1 to 1 in place.
+type Filter interface {
+	Filter(samples []float64, sampleRate int)
+}
+
+// Chain runs a list of filters in order.
+type Chain []Filter
+
+// Filter passes the samples through each filter in the chain.
+func (c Chain) Filter(samples []float64, sampleRate int) {
+	for _, f := range c {
+		f.Filter(samples, sampleRate)
+	}
+}
+
+// Process runs the filter over signed 16-bit little endian mono PCM and
+// returns the filtered PCM. Samples outside of the 16-bit range are clipped.
+func Process(f Filter, pcm []byte, sampleRate int) []byte {
+	samples := Decode(pcm)
+	f.Filter(samples, sampleRate)
+	return Encode(samples)
+}
+
+// Decode converts 16-bit PCM to samples in the range -1 to 1.
+func Decode(pcm []byte) []float64 {
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / math.MaxInt16
+	}
+	return samples
+}
+
+// Encode converts samples in the range -1 to 1 to 16-bit PCM.
+func Encode(samples []float64) []byte {
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		v := math.Round(s * math.MaxInt16)
+		v = min(max(v, math.MinInt16), math.MaxInt16)
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(v)))
+	}
+	return pcm
+}
+
+// DB converts an amplitude to dBFS.
+func DB(amplitude float64) float64 {
+	return 20 * math.Log10(amplitude)
+}
+
+// Amplitude converts dBFS to an amplitude.
+func Amplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
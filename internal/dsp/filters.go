@@ -0,0 +1,192 @@
+package dsp
+
+import (
+	"cmp"
+	"math"
+	"time"
+)
+
+// RemoveDC subtracts the mean of the samples, which some cheap microphones
+// and sound cards add to the signal.
+type RemoveDC struct{}
+
+// Filter implements Filter.
+func (RemoveDC) Filter(samples []float64, sampleRate int) {
+	if len(samples) == 0 {
+		return
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / float64(len(samples))
+	for i := range samples {
+		samples[i] -= mean
+	}
+}
+
+// HighPass removes rumble and handling noise below the cutoff frequency
+// with a second order Butterworth filter.
+type HighPass struct {
+	Cutoff float64 // Hz
+}
+
+// Filter implements Filter.
+func (h HighPass) Filter(samples []float64, sampleRate int) {
+	if h.Cutoff <= 0 || h.Cutoff >= float64(sampleRate)/2 {
+		return
+	}
+	// biquad coefficients from the Audio EQ Cookbook with Q = 1/sqrt(2)
+	w := 2 * math.Pi * h.Cutoff / float64(sampleRate)
+	alpha := math.Sin(w) / math.Sqrt2
+	cos := math.Cos(w)
+	a0 := 1 + alpha
+	b0 := (1 + cos) / 2 / a0
+	b1 := -(1 + cos) / a0
+	b2 := b0
+	a1 := -2 * cos / a0
+	a2 := (1 - alpha) / a0
+	var x1, x2, y1, y2 float64
+	for i, x := range samples {
+		y := b0*x + b1*x1 + b2*x2 - a1*y1 - a2*y2
+		x2, x1 = x1, x
+		y2, y1 = y1, y
+		samples[i] = y
+	}
+}
+
+// Defaults for the zero values of the Gate fields.
+const (
+	DefaultGateHold = 200 * time.Millisecond
+	DefaultGateFade = 10 * time.Millisecond
+)
+
+// gateFrame is the length of the frames whose level opens the gate.
+const gateFrame = 10 * time.Millisecond
+
+// sampleCount returns the number of samples in d, at least one. It counts in
+// milliseconds so that it doesn't overflow a 32-bit int.
+func sampleCount(d time.Duration, sampleRate int) int {
+	return max(sampleRate*int(d/time.Millisecond)/1000, 1)
+}
+
+// Gate silences the audio while it's quieter than the threshold, so that
+// background noise between words isn't transcribed.
+type Gate struct {
+	Threshold float64 // dBFS
+	// Hold keeps the gate open after the level drops, so that the quiet
+	// ends of words aren't cut off.
+	Hold time.Duration
+	// Fade is how long the gate takes to open and close, to avoid clicks.
+	Fade time.Duration
+}
+
+// Filter implements Filter.
+func (g Gate) Filter(samples []float64, sampleRate int) {
+	frame := sampleCount(gateFrame, sampleRate)
+	hold := int(cmp.Or(g.Hold, DefaultGateHold) / gateFrame)
+	fade := sampleCount(cmp.Or(g.Fade, DefaultGateFade), sampleRate)
+	threshold := Amplitude(g.Threshold)
+	// open[i] reports whether the gate is open for frame i
+	open := make([]bool, (len(samples)+frame-1)/frame)
+	closeAt := -1
+	for i := range open {
+		if rms(samples[i*frame:min((i+1)*frame, len(samples))]) >= threshold {
+			closeAt = i + hold
+		}
+		open[i] = i <= closeAt
+	}
+	// the gain fades in before and out after the open frames
+	gain := make([]float64, len(samples))
+	dist := fade
+	for i := range samples {
+		if open[i/frame] {
+			dist = 0
+		} else {
+			dist = min(dist+1, fade)
+		}
+		gain[i] = 1 - float64(dist)/float64(fade)
+	}
+	dist = fade
+	for i := len(samples) - 1; i >= 0; i-- {
+		if open[i/frame] {
+			dist = 0
+		} else {
+			dist = min(dist+1, fade)
+		}
+		samples[i] *= max(gain[i], 1-float64(dist)/float64(fade))
+	}
+}
+
+// Defaults for the zero values of the Normalize fields.
+const (
+	DefaultPeakTarget = -1
+	DefaultRMSTarget  = -20
+	DefaultMaxGain    = 20
+)
+
+// activeDB is the level of the frames which count towards the RMS level.
+const activeDB = -50
+
+// Normalize amplifies quiet recordings to the target level.
+type Normalize struct {
+	// RMS normalizes the average level of the speech instead of the peak.
+	// Frames quieter than -50 dBFS are ignored, and the gain is limited so
+	// that the peak doesn't clip.
+	RMS bool
+	// Target is the level in dBFS, zero for the default of the mode.
+	Target float64
+	// MaxGain limits the amplification in dB, so that silence isn't
+	// turned into loud noise.
+	MaxGain float64
+}
+
+// Filter implements Filter.
+func (n Normalize) Filter(samples []float64, sampleRate int) {
+	var peak float64
+	for _, s := range samples {
+		peak = max(peak, math.Abs(s))
+	}
+	if peak == 0 {
+		return
+	}
+	var gain float64
+	if n.RMS {
+		frame := sampleCount(gateFrame, sampleRate)
+		var sum float64
+		var count int
+		for i := 0; i < len(samples); i += frame {
+			f := samples[i:min(i+frame, len(samples))]
+			if rms(f) >= Amplitude(activeDB) {
+				for _, s := range f {
+					sum += s * s
+				}
+				count += len(f)
+			}
+		}
+		if count == 0 {
+			return
+		}
+		level := math.Sqrt(sum / float64(count))
+		gain = Amplitude(cmp.Or(n.Target, DefaultRMSTarget)) / level
+		gain = min(gain, Amplitude(DefaultPeakTarget)/peak)
+	} else {
+		gain = Amplitude(cmp.Or(n.Target, DefaultPeakTarget)) / peak
+	}
+	gain = min(gain, Amplitude(cmp.Or(n.MaxGain, DefaultMaxGain)))
+	for i := range samples {
+		samples[i] *= gain
+	}
+}
+
+// rms returns the root mean square of the samples.
+func rms(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
@@ -0,0 +1,263 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const rate = 16000
+
+// tone returns d of a sine wave at freq Hz with the amplitude.
+func tone(freq, amplitude float64, d time.Duration) []float64 {
+	samples := make([]float64, int(d.Seconds()*rate))
+	for i := range samples {
+		samples[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/rate)
+	}
+	return samples
+}
+
+// at returns the index of the sample at time d.
+func at(d time.Duration) int {
+	return int(d.Seconds() * rate)
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestRemoveDC(t *testing.T) {
+	sine := tone(100, 0.3, time.Second)
+	samples := make([]float64, len(sine))
+	for i, s := range sine {
+		samples[i] = s + 0.2
+	}
+	RemoveDC{}.Filter(samples, rate)
+	for i := range samples {
+		if !near(samples[i], sine[i], 1e-9) {
+			t.Fatalf("sample %d is %v, want %v", i, samples[i], sine[i])
+		}
+	}
+	RemoveDC{}.Filter(nil, rate)
+}
+
+func TestHighPass(t *testing.T) {
+	tests := []struct {
+		freq float64
+		gain float64 // dB
+	}{
+		{freq: 25, gain: -24}, // two octaves below, 12dB per octave
+		{freq: 50, gain: -12}, // an octave below
+		{freq: 100, gain: -3}, // the cutoff
+		{freq: 1000, gain: 0}, // speech is untouched
+		{freq: 4000, gain: 0},
+	}
+	for _, tt := range tests {
+		samples := tone(tt.freq, 0.5, 2*time.Second)
+		HighPass{Cutoff: 100}.Filter(samples, rate)
+		// skip the filter's settling time
+		gain := DB(rms(samples[rate:]) / (0.5 / math.Sqrt2))
+		if !near(gain, tt.gain, 0.5) {
+			t.Errorf("%vHz: got %.1fdB, want %vdB", tt.freq, gain, tt.gain)
+		}
+	}
+	// cutoffs outside of the audible range are ignored
+	for _, cutoff := range []float64{0, rate / 2} {
+		samples := tone(20, 0.5, time.Second)
+		want := tone(20, 0.5, time.Second)
+		HighPass{Cutoff: cutoff}.Filter(samples, rate)
+		for i := range samples {
+			if samples[i] != want[i] {
+				t.Fatalf("cutoff %v: sample %d changed", cutoff, i)
+			}
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	// quiet noise, a word, then quiet noise again
+	var samples []float64
+	samples = append(samples, tone(1000, 0.001, 500*time.Millisecond)...)
+	samples = append(samples, tone(200, 0.5, 300*time.Millisecond)...)
+	samples = append(samples, tone(1000, 0.001, time.Second)...)
+	tests := []struct {
+		name string
+		gate Gate
+		// open and closed are times where the gate is fully open or closed
+		open   []time.Duration
+		closed []time.Duration
+		// faded is whether the gate is half open 5ms before the word
+		faded bool
+	}{
+		{
+			name: "default hold",
+			gate: Gate{Threshold: -40},
+			open: []time.Duration{
+				500 * time.Millisecond,
+				600 * time.Millisecond,
+				950 * time.Millisecond, // within the hold after the word
+			},
+			closed: []time.Duration{
+				100 * time.Millisecond,
+				480 * time.Millisecond,
+				1100 * time.Millisecond,
+				1500 * time.Millisecond,
+			},
+			faded: true,
+		},
+		{
+			name:   "short hold",
+			gate:   Gate{Threshold: -40, Hold: 50 * time.Millisecond},
+			open:   []time.Duration{600 * time.Millisecond, 840 * time.Millisecond},
+			closed: []time.Duration{900 * time.Millisecond, 1500 * time.Millisecond},
+			faded:  true,
+		},
+		{
+			name: "below the threshold",
+			gate: Gate{Threshold: -70},
+			open: []time.Duration{100 * time.Millisecond, 600 * time.Millisecond, 1500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]float64(nil), samples...)
+			tt.gate.Filter(got, rate)
+			// the gate opens over 10ms before the word to avoid a click
+			if i := at(495*time.Millisecond) + 3; tt.faded && !near(got[i]/samples[i], 0.5, 0.05) {
+				t.Errorf("got a gain of %.2f halfway through the fade, want 0.5", got[i]/samples[i])
+			}
+			for _, d := range tt.open {
+				i := at(d)
+				if got[i] != samples[i] {
+					t.Errorf("%v: got %v, want the gate open: %v", d, got[i], samples[i])
+				}
+			}
+			for _, d := range tt.closed {
+				i := at(d)
+				if got[i] != 0 {
+					t.Errorf("%v: got %v, want the gate closed", d, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	peak := func(samples []float64) float64 {
+		var p float64
+		for _, s := range samples {
+			p = max(p, math.Abs(s))
+		}
+		return p
+	}
+	spike := tone(200, 0.02, time.Second)
+	spike[rate/2] = 0.5
+	tests := []struct {
+		name      string
+		normalize Normalize
+		samples   []float64
+		peak      float64 // dBFS
+		rms       float64 // dBFS of the whole signal, 0 to not check it
+	}{
+		{
+			name:    "peak",
+			samples: tone(200, 0.1, time.Second),
+			peak:    DefaultPeakTarget,
+		},
+		{
+			name:      "peak target",
+			normalize: Normalize{Target: -6},
+			samples:   tone(200, 0.1, time.Second),
+			peak:      -6,
+		},
+		{
+			name:    "loud audio is attenuated",
+			samples: tone(200, 1, time.Second),
+			peak:    DefaultPeakTarget,
+		},
+		{
+			name:    "max gain",
+			samples: tone(200, 0.001, time.Second),
+			peak:    DB(0.001) + DefaultMaxGain,
+		},
+		{
+			name:      "custom max gain",
+			normalize: Normalize{MaxGain: 6},
+			samples:   tone(200, 0.01, time.Second),
+			peak:      DB(0.01) + 6,
+		},
+		{
+			name:      "rms",
+			normalize: Normalize{RMS: true},
+			samples:   tone(200, 0.05, time.Second),
+			peak:      DefaultRMSTarget + DB(math.Sqrt2),
+			rms:       DefaultRMSTarget,
+		},
+		{
+			name:      "rms ignores silence",
+			normalize: Normalize{RMS: true},
+			samples:   append(tone(200, 0.05, time.Second), make([]float64, rate)...),
+			peak:      DefaultRMSTarget + DB(math.Sqrt2),
+		},
+		{
+			name:      "rms limited by the peak",
+			normalize: Normalize{RMS: true},
+			samples:   spike,
+			peak:      DefaultPeakTarget,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.normalize.Filter(tt.samples, rate)
+			if got := DB(peak(tt.samples)); !near(got, tt.peak, 0.1) {
+				t.Errorf("got a peak of %.2fdBFS, want %.2f", got, tt.peak)
+			}
+			if tt.rms != 0 {
+				if got := DB(rms(tt.samples)); !near(got, tt.rms, 0.1) {
+					t.Errorf("got an RMS of %.2fdBFS, want %.2f", got, tt.rms)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeSilence(t *testing.T) {
+	for _, n := range []Normalize{{}, {RMS: true}} {
+		for _, samples := range [][]float64{
+			nil,
+			make([]float64, rate),
+			tone(200, Amplitude(activeDB-10), time.Second),
+		} {
+			want := append([]float64(nil), samples...)
+			n.Filter(samples, rate)
+			for i, s := range samples {
+				if math.IsNaN(s) || math.IsInf(s, 0) {
+					t.Fatalf("%+v: sample %d is %v", n, i, s)
+				}
+				if n.RMS && s != want[i] {
+					t.Fatalf("%+v: sample %d of quiet audio changed", n, i)
+				}
+			}
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	pcm := Encode([]float64{0, 0.5, -0.5, 2, -2})
+	samples := Decode(pcm)
+	want := []float64{0, 0.5, -0.5, 1, -32768.0 / 32767}
+	for i := range want {
+		if !near(samples[i], want[i], 1e-4) {
+			t.Errorf("sample %d is %v, want %v", i, samples[i], want[i])
+		}
+	}
+	// the chain runs the filters in order
+	got := Decode(Process(Chain{Normalize{Target: -6}, Normalize{MaxGain: 1}}, Encode(tone(200, 0.1, time.Second)), rate))
+	var peak float64
+	for _, s := range got {
+		peak = max(peak, math.Abs(s))
+	}
+	if !near(DB(peak), -5, 0.1) {
+		t.Errorf("got a peak of %.2fdBFS, want -5", DB(peak))
+	}
+}
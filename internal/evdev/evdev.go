@@ -7,15 +7,16 @@ import (
 	"github.com/icholy/whisperd/internal/inputcodes"
 )
 
-// WaitForKey blocks until the specified key event (code and value) is received from the device.
-func WaitForKey(device *os.File, code uint16, value int32) error {
+// ReadKeys reads key events from the device and sends them to the keys channel.
+// It blocks until reading from the device fails.
+func ReadKeys(device *os.File, keys chan<- inputcodes.Event) error {
 	for {
 		var e inputcodes.Event
 		if err := binary.Read(device, binary.LittleEndian, &e); err != nil {
 			return err
 		}
-		if e.Type == inputcodes.EV_KEY && e.Code == code && e.Value == value {
-			return nil
+		if e.Type == inputcodes.EV_KEY {
+			keys <- e
 		}
 	}
 }
@@ -0,0 +1,70 @@
+package flac
+
+// bitWriter packs values most significant bit first.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64 // pending bits, right aligned
+	nbits int    // number of pending bits, less than 8
+}
+
+// write appends the low n bits of v.
+func (bw *bitWriter) write(v uint64, n int) {
+	for n > 0 {
+		// keep the accumulator below 64 bits
+		chunk := min(n, 32)
+		n -= chunk
+		bw.acc = bw.acc<<chunk | (v>>n)&(1<<chunk-1)
+		bw.nbits += chunk
+		for bw.nbits >= 8 {
+			bw.nbits -= 8
+			bw.buf = append(bw.buf, byte(bw.acc>>bw.nbits))
+		}
+		bw.acc &= 1<<bw.nbits - 1
+	}
+}
+
+// writeSigned appends v as an n-bit two's complement value.
+func (bw *bitWriter) writeSigned(v int32, n int) {
+	bw.write(uint64(uint32(v))&(1<<n-1), n)
+}
+
+// writeRice appends v rice coded with parameter k: the quotient in unary
+// followed by the low k bits.
+func (bw *bitWriter) writeRice(v uint32, k int) {
+	q := int(v >> k)
+	for q >= 32 {
+		bw.write(0, 32)
+		q -= 32
+	}
+	bw.write(1, q+1)
+	bw.write(uint64(v), k)
+}
+
+// writeUTF8 appends v in the extended UTF-8 coding used for frame numbers.
+func (bw *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		bw.write(v, 8)
+		return
+	}
+	// count the continuation bytes, each holds 6 bits
+	n := 1
+	for v >= 1<<(6*n+6-n) {
+		n++
+	}
+	bw.write(0xff<<(7-n)&0xff|v>>(6*n), 8)
+	for i := n - 1; i >= 0; i-- {
+		bw.write(0x80|(v>>(6*i))&0x3f, 8)
+	}
+}
+
+// align pads the last byte with zero bits.
+func (bw *bitWriter) align() {
+	if bw.nbits > 0 {
+		bw.write(0, 8-bw.nbits)
+	}
+}
+
+// bytes returns the complete bytes written so far.
+func (bw *bitWriter) bytes() []byte {
+	return bw.buf
+}
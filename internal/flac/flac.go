@@ -0,0 +1,250 @@
+// Package flac encodes 16-bit PCM as FLAC, which is about half the size
+// of WAV for speech and is accepted by the transcription API.
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// Options specifies the FLAC encoding parameters.
+type Options struct {
+	NumChannels int
+	SampleRate  int
+}
+
+const (
+	blockSize = 4096
+	// maxFixedOrder is the highest order of the fixed predictors
+	maxFixedOrder = 4
+	// maxPartitionOrder limits the number of rice partitions to 2^8
+	maxPartitionOrder = 8
+	// maxRiceParam is the highest 4-bit rice parameter, 15 is the escape code
+	maxRiceParam = 14
+)
+
+// Write writes the interleaved little endian 16-bit PCM data as a FLAC file.
+func Write(w io.Writer, pcm []byte, opt Options) error {
+	if opt.NumChannels < 1 || opt.NumChannels > 8 {
+		return errors.New("flac: unsupported number of channels")
+	}
+	if opt.SampleRate < 1 || opt.SampleRate >= 1<<20 {
+		return errors.New("flac: unsupported sample rate")
+	}
+	channels := opt.NumChannels
+	total := len(pcm) / 2 / channels
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	writeStreamInfo(&buf, opt, total)
+	samples := make([][]int32, channels)
+	for start, frame := 0, 0; start < total; start, frame = start+blockSize, frame+1 {
+		n := min(blockSize, total-start)
+		for c := range samples {
+			samples[c] = samples[c][:0]
+			for i := range n {
+				off := ((start+i)*channels + c) * 2
+				samples[c] = append(samples[c], int32(int16(binary.LittleEndian.Uint16(pcm[off:]))))
+			}
+		}
+		writeFrame(&buf, frame, samples)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeStreamInfo writes the STREAMINFO metadata block. The frame sizes
+// and the MD5 signature are left unknown.
+func writeStreamInfo(buf *bytes.Buffer, opt Options, total int) {
+	var bw bitWriter
+	bw.write(1, 1)   // last metadata block
+	bw.write(0, 7)   // STREAMINFO
+	bw.write(34, 24) // length
+	bw.write(blockSize, 16)
+	bw.write(blockSize, 16)
+	bw.write(0, 24) // minimum frame size
+	bw.write(0, 24) // maximum frame size
+	bw.write(uint64(opt.SampleRate), 20)
+	bw.write(uint64(opt.NumChannels-1), 3)
+	bw.write(16-1, 5)
+	bw.write(uint64(total), 36)
+	bw.write(0, 64) // MD5
+	bw.write(0, 64)
+	buf.Write(bw.bytes())
+}
+
+// writeFrame writes a frame with the samples of each channel.
+func writeFrame(buf *bytes.Buffer, number int, samples [][]int32) {
+	n := len(samples[0])
+	var bw bitWriter
+	bw.write(0x3ffe, 14) // sync code
+	bw.write(0, 1)
+	bw.write(0, 1)   // fixed block size
+	bw.write(0x7, 4) // block size in 16 bits at the end of the header
+	bw.write(0, 4)   // sample rate from STREAMINFO
+	bw.write(uint64(len(samples)-1), 4)
+	bw.write(0x4, 3) // 16 bits per sample
+	bw.write(0, 1)
+	bw.writeUTF8(uint64(number))
+	bw.write(uint64(n-1), 16)
+	bw.write(uint64(crc8(bw.bytes())), 8)
+	for _, s := range samples {
+		writeSubframe(&bw, s)
+	}
+	bw.align()
+	bw.write(uint64(crc16(bw.bytes())), 16)
+	buf.Write(bw.bytes())
+}
+
+// writeSubframe writes the samples of a channel with the smallest encoding.
+func writeSubframe(bw *bitWriter, samples []int32) {
+	if constant(samples) {
+		bw.write(0, 1)
+		bw.write(0, 6) // CONSTANT
+		bw.write(0, 1)
+		bw.writeSigned(samples[0], 16)
+		return
+	}
+	// the predictor with the smallest residual is usually the one which
+	// codes best, like the reference encoder only that one is rice coded
+	best, bestSum := 0, uint64(math.MaxUint64)
+	var residual []int32
+	for order := 0; order <= maxFixedOrder && order < len(samples); order++ {
+		r := fixedResidual(samples, order)
+		var sum uint64
+		for _, v := range r {
+			sum += uint64(zigzag(v))
+		}
+		if sum < bestSum {
+			best, bestSum, residual = order, sum, r
+		}
+	}
+	partOrder, params, size := riceParams(residual, len(samples), best)
+	if size+best*16 >= len(samples)*16 {
+		bw.write(0, 1)
+		bw.write(1, 6) // VERBATIM
+		bw.write(0, 1)
+		for _, s := range samples {
+			bw.writeSigned(s, 16)
+		}
+		return
+	}
+	bw.write(0, 1)
+	bw.write(uint64(8|best), 6) // FIXED
+	bw.write(0, 1)
+	for _, s := range samples[:best] {
+		bw.writeSigned(s, 16)
+	}
+	bw.write(0, 2) // rice coding with 4-bit parameters
+	bw.write(uint64(partOrder), 4)
+	partSize := len(samples) >> partOrder
+	for p, k := range params {
+		count := partSize
+		if p == 0 {
+			count -= best
+		}
+		bw.write(uint64(k), 4)
+		for _, r := range residual[:count] {
+			bw.writeRice(zigzag(r), k)
+		}
+		residual = residual[count:]
+	}
+}
+
+// constant reports whether all samples are the same.
+func constant(samples []int32) bool {
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// fixedResidual returns the residual of the fixed predictor of the given
+// order, which starts after the first order samples.
+func fixedResidual(s []int32, order int) []int32 {
+	residual := make([]int32, 0, len(s)-order)
+	for i := order; i < len(s); i++ {
+		var r int32
+		switch order {
+		case 0:
+			r = s[i]
+		case 1:
+			r = s[i] - s[i-1]
+		case 2:
+			r = s[i] - 2*s[i-1] + s[i-2]
+		case 3:
+			r = s[i] - 3*s[i-1] + 3*s[i-2] - s[i-3]
+		case 4:
+			r = s[i] - 4*s[i-1] + 6*s[i-2] - 4*s[i-3] + s[i-4]
+		}
+		residual = append(residual, r)
+	}
+	return residual
+}
+
+// riceParams chooses the partition order and the rice parameter of each
+// partition which minimize the size of the residual, and returns the size
+// in bits including the partition headers.
+func riceParams(residual []int32, n, predOrder int) (int, []int, int) {
+	bestOrder, bestSize := 0, math.MaxInt
+	var bestParams []int
+	for order := 0; order <= maxPartitionOrder; order++ {
+		partSize := n >> order
+		if n%(1<<order) != 0 || partSize <= predOrder {
+			break
+		}
+		size := 6 // coding method and partition order
+		params := make([]int, 0, 1<<order)
+		rest := residual
+		for p := range 1 << order {
+			count := partSize
+			if p == 0 {
+				count -= predOrder
+			}
+			k, cost := riceParam(rest[:count])
+			params = append(params, k)
+			size += 4 + cost
+			rest = rest[count:]
+		}
+		if size < bestSize {
+			bestOrder, bestSize, bestParams = order, size, params
+		}
+	}
+	return bestOrder, bestParams, bestSize
+}
+
+// riceParam returns the best rice parameter for the residual and the
+// number of bits it takes.
+func riceParam(residual []int32) (int, int) {
+	var sum uint64
+	for _, r := range residual {
+		sum += uint64(zigzag(r))
+	}
+	// the optimal parameter is close to log2 of the mean
+	guess := 0
+	if mean := sum / uint64(max(len(residual), 1)); mean > 0 {
+		guess = bits.Len64(mean) - 1
+	}
+	bestK, bestBits := 0, math.MaxInt
+	lo := min(max(guess-1, 0), maxRiceParam)
+	for k := lo; k <= min(guess+1, maxRiceParam); k++ {
+		size := len(residual) * (k + 1)
+		for _, r := range residual {
+			size += int(zigzag(r) >> k)
+		}
+		if size < bestBits {
+			bestK, bestBits = k, size
+		}
+	}
+	return bestK, bestBits
+}
+
+// zigzag maps signed values to unsigned ones: 0, -1, 1, -2 => 0, 1, 2, 3.
+func zigzag(v int32) uint32 {
+	return uint32(v<<1) ^ uint32(v>>31)
+}
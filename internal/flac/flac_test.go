@@ -0,0 +1,392 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bitReader reads values most significant bit first.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (br *bitReader) read(n int) (uint64, error) {
+	if br.pos+n > len(br.data)*8 {
+		return 0, fmt.Errorf("unexpected end of data at bit %d", br.pos)
+	}
+	var v uint64
+	for range n {
+		v = v<<1 | uint64(br.data[br.pos/8]>>(7-br.pos%8)&1)
+		br.pos++
+	}
+	return v, nil
+}
+
+func (br *bitReader) readSigned(n int) (int32, error) {
+	v, err := br.read(n)
+	return int32(int64(v<<(64-n)) >> (64 - n)), err
+}
+
+// decoded is a FLAC file read back by decode.
+type decoded struct {
+	sampleRate int
+	channels   int
+	total      int
+	pcm        []byte
+	// subframes are the types of the subframes: 0 CONSTANT, 1 VERBATIM
+	// and 8 plus the order for FIXED
+	subframes []int
+}
+
+// decode reads the subset of FLAC written by Write, checking the header
+// and frame CRCs.
+func decode(data []byte) (*decoded, error) {
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return nil, fmt.Errorf("missing fLaC marker")
+	}
+	br := &bitReader{data: data, pos: 32}
+	var err error
+	read := func(n int) int {
+		v, rerr := br.read(n)
+		if err == nil {
+			err = rerr
+		}
+		return int(v)
+	}
+	readSigned := func(n int) int32 {
+		v, rerr := br.readSigned(n)
+		if err == nil {
+			err = rerr
+		}
+		return v
+	}
+	if last, typ, length := read(1), read(7), read(24); last != 1 || typ != 0 || length != 34 {
+		return nil, fmt.Errorf("bad STREAMINFO header: %d %d %d", last, typ, length)
+	}
+	read(16 + 16 + 24 + 24) // block and frame sizes
+	d := &decoded{sampleRate: read(20), channels: read(3) + 1}
+	if bps := read(5) + 1; bps != 16 {
+		return nil, fmt.Errorf("got %d bits per sample", bps)
+	}
+	d.total = read(36)
+	read(128) // MD5
+	samples := make([][]int32, d.channels)
+	for frame := 0; err == nil && br.pos/8 < len(data); frame++ {
+		start := br.pos / 8
+		if sync := read(14); sync != 0x3ffe {
+			return nil, fmt.Errorf("frame %d: bad sync code %#x", frame, sync)
+		}
+		read(2)
+		blockSize, rate, channels, size := read(4), read(4), read(4)+1, read(3)
+		read(1)
+		if blockSize != 7 || rate != 0 || channels != d.channels || size != 4 {
+			return nil, fmt.Errorf("frame %d: bad header %d %d %d %d", frame, blockSize, rate, channels, size)
+		}
+		// the frame number in extended UTF-8
+		number := read(8)
+		if extra := bitsLeadingOnes(number); extra > 0 {
+			number &= 1<<(7-extra) - 1
+			for range extra - 1 {
+				number = number<<6 | read(8)&0x3f
+			}
+		}
+		if number != frame {
+			return nil, fmt.Errorf("got frame number %d, want %d", number, frame)
+		}
+		n := read(16) + 1
+		if crc := read(8); err == nil && crc != int(crc8(data[start:br.pos/8-1])) {
+			return nil, fmt.Errorf("frame %d: bad header CRC", frame)
+		}
+		for c := range samples {
+			read(1)
+			typ := read(6)
+			read(1)
+			d.subframes = append(d.subframes, typ)
+			switch {
+			case typ == 0:
+				v := readSigned(16)
+				for range n {
+					samples[c] = append(samples[c], v)
+				}
+			case typ == 1:
+				for range n {
+					samples[c] = append(samples[c], readSigned(16))
+				}
+			case typ >= 8 && typ <= 12:
+				order := typ - 8
+				s := make([]int32, 0, n)
+				for range order {
+					s = append(s, readSigned(16))
+				}
+				if method := read(2); method != 0 {
+					return nil, fmt.Errorf("frame %d: rice coding method %d", frame, method)
+				}
+				partOrder := read(4)
+				for p := range 1 << partOrder {
+					count := n >> partOrder
+					if p == 0 {
+						count -= order
+					}
+					k := read(4)
+					for range count {
+						q := 0
+						for err == nil && read(1) == 0 {
+							q++
+						}
+						v := uint32(q<<k | read(k))
+						residual := int32(v>>1) ^ -int32(v&1)
+						s = append(s, residual+predict(s, order))
+					}
+				}
+				samples[c] = append(samples[c], s...)
+			default:
+				return nil, fmt.Errorf("frame %d: unexpected subframe type %d", frame, typ)
+			}
+		}
+		br.pos = (br.pos + 7) / 8 * 8
+		if crc := read(16); err == nil && crc != int(crc16(data[start:br.pos/8-2])) {
+			return nil, fmt.Errorf("frame %d: bad frame CRC", frame)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for c := range samples {
+		if len(samples[c]) != d.total {
+			return nil, fmt.Errorf("channel %d has %d samples, want %d", c, len(samples[c]), d.total)
+		}
+	}
+	for i := range d.total {
+		for c := range samples {
+			d.pcm = binary.LittleEndian.AppendUint16(d.pcm, uint16(int16(samples[c][i])))
+		}
+	}
+	return d, nil
+}
+
+// bitsLeadingOnes returns the number of leading one bits of a byte.
+func bitsLeadingOnes(b int) int {
+	n := 0
+	for n < 8 && b&(0x80>>n) != 0 {
+		n++
+	}
+	return n
+}
+
+// predict returns the fixed prediction of the next sample.
+func predict(s []int32, order int) int32 {
+	i := len(s)
+	switch order {
+	case 1:
+		return s[i-1]
+	case 2:
+		return 2*s[i-1] - s[i-2]
+	case 3:
+		return 3*s[i-1] - 3*s[i-2] + s[i-3]
+	case 4:
+		return 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+	}
+	return 0
+}
+
+// pcm16 returns the samples as 16-bit little endian PCM.
+func pcm16(samples ...int) []byte {
+	var pcm []byte
+	for _, s := range samples {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(s)))
+	}
+	return pcm
+}
+
+// generate returns n samples of fn.
+func generate(n int, fn func(i int) int) []int {
+	samples := make([]int, n)
+	for i := range samples {
+		samples[i] = fn(i)
+	}
+	return samples
+}
+
+func TestWrite(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sine := func(i int) int { return int(10000 * math.Sin(float64(i)/10)) }
+	tests := []struct {
+		name     string
+		channels int
+		samples  []int
+		// subframes are the expected subframe types, nil to not check them
+		subframes []int
+	}{
+		{
+			name:     "empty",
+			channels: 1,
+		},
+		{
+			name:      "single sample",
+			channels:  1,
+			samples:   []int{1234},
+			subframes: []int{0},
+		},
+		{
+			name:      "constant block",
+			channels:  1,
+			samples:   generate(4096, func(int) int { return -7 }),
+			subframes: []int{0},
+		},
+		{
+			name:      "silence",
+			channels:  1,
+			samples:   make([]int, 4096),
+			subframes: []int{0},
+		},
+		{
+			name:      "short final block",
+			channels:  1,
+			samples:   generate(4096+1000, sine),
+			subframes: []int{12, 12},
+		},
+		{
+			name:     "fixed predictor shorter than its order",
+			channels: 1,
+			samples:  []int{1, 5, 2},
+		},
+		{
+			name:      "verbatim fallback for noise",
+			channels:  1,
+			samples:   generate(4096, func(int) int { return rng.Intn(65536) - 32768 }),
+			subframes: []int{1},
+		},
+		{
+			name:     "full scale",
+			channels: 1,
+			samples: generate(4096*2+17, func(i int) int {
+				if i%2 == 0 {
+					return -32768
+				}
+				return 32767
+			}),
+		},
+		{
+			name:     "full scale square",
+			channels: 1,
+			samples: generate(4096, func(i int) int {
+				if i/3%2 == 0 {
+					return -32768
+				}
+				return 32767
+			}),
+		},
+		{
+			name:     "stereo",
+			channels: 2,
+			samples: generate(2*5000, func(i int) int {
+				if i%2 == 0 {
+					return sine(i / 2)
+				}
+				return 3
+			}),
+			subframes: []int{12, 0, 12, 0},
+		},
+		{
+			name:     "many frames",
+			channels: 1,
+			samples:  generate(16000*40, sine),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := pcm16(tt.samples...)
+			var buf bytes.Buffer
+			if err := Write(&buf, pcm, Options{NumChannels: tt.channels, SampleRate: 16000}); err != nil {
+				t.Fatal(err)
+			}
+			d, err := decode(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.sampleRate != 16000 || d.channels != tt.channels {
+				t.Errorf("got %dHz with %d channels", d.sampleRate, d.channels)
+			}
+			if want := len(tt.samples) / tt.channels; d.total != want {
+				t.Errorf("got %d samples, want %d", d.total, want)
+			}
+			if !bytes.Equal(d.pcm, pcm) {
+				t.Error("decoded PCM doesn't match")
+			}
+			if tt.subframes != nil && fmt.Sprint(d.subframes) != fmt.Sprint(tt.subframes) {
+				t.Errorf("got subframe types %v, want %v", d.subframes, tt.subframes)
+			}
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	for _, opt := range []Options{
+		{NumChannels: 0, SampleRate: 16000},
+		{NumChannels: 9, SampleRate: 16000},
+		{NumChannels: 1, SampleRate: 0},
+		{NumChannels: 1, SampleRate: 1 << 20},
+	} {
+		if err := Write(&bytes.Buffer{}, nil, opt); err == nil {
+			t.Errorf("%+v: expected an error", opt)
+		}
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, nil, Options{NumChannels: 1, SampleRate: 16000}); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		'f', 'L', 'a', 'C',
+		0x80, 0x00, 0x00, 0x22, // last block, STREAMINFO, 34 bytes
+		0x10, 0x00, 0x10, 0x00, // block sizes
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // unknown frame sizes
+		0x03, 0xe8, 0x00, 0xf0, // 16000Hz, mono, 16 bits
+		0x00, 0x00, 0x00, 0x00, // no samples
+	}
+	want = append(want, make([]byte, 16)...) // MD5
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got % x\nwant % x", buf.Bytes(), want)
+	}
+}
+
+func TestWriteUTF8(t *testing.T) {
+	// frame numbers from the FLAC specification's extended UTF-8 coding
+	tests := []struct {
+		v    uint64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0xc2, 0x80}},
+		{0x7ff, []byte{0xdf, 0xbf}},
+		{0x800, []byte{0xe0, 0xa0, 0x80}},
+		{0xffff, []byte{0xef, 0xbf, 0xbf}},
+		{0x10000, []byte{0xf0, 0x90, 0x80, 0x80}},
+		{0x7fffffff, []byte{0xfd, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf}},
+	}
+	for _, tt := range tests {
+		var bw bitWriter
+		bw.writeUTF8(tt.v)
+		if got := bw.bytes(); !bytes.Equal(got, tt.want) {
+			t.Errorf("writeUTF8(%#x) = % x, want % x", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// check values of the CRC-8 and CRC-16/UMTS used by FLAC
+	if got := crc8([]byte("123456789")); got != 0xf4 {
+		t.Errorf("crc8 = %#x, want 0xf4", got)
+	}
+	if got := crc16([]byte("123456789")); got != 0xfee8 {
+		t.Errorf("crc16 = %#x, want 0xfee8", got)
+	}
+}
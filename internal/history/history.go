@@ -0,0 +1,59 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSize is the number of entries kept when Size is zero.
+const DefaultSize = 10
+
+// Entry is a single transcript.
+type Entry struct {
+	Time time.Time
+	Text string
+}
+
+// History keeps the most recent transcripts in memory.
+// It is safe for concurrent use.
+type History struct {
+	Size int
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Add records a new transcript, discarding the oldest entry if the history is full.
+func (h *History) Add(text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	size := h.Size
+	if size <= 0 {
+		size = DefaultSize
+	}
+	h.entries = append(h.entries, Entry{Time: time.Now(), Text: text})
+	if n := len(h.entries); n > size {
+		h.entries = append(h.entries[:0], h.entries[n-size:]...)
+	}
+}
+
+// Last returns the most recent transcript.
+func (h *History) Last() (Entry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) == 0 {
+		return Entry{}, false
+	}
+	return h.entries[len(h.entries)-1], true
+}
+
+// Entries returns a copy of the history, newest first.
+func (h *History) Entries() []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := make([]Entry, len(h.entries))
+	for i, e := range h.entries {
+		entries[len(entries)-1-i] = e
+	}
+	return entries
+}
@@ -1,60 +1,5 @@
 package inputcodes
 
-// Keys is a list of key codes for supported keyboard keys.
-var Keys = []uint16{
-	KEY_0,
-	KEY_1,
-	KEY_2,
-	KEY_3,
-	KEY_4,
-	KEY_5,
-	KEY_6,
-	KEY_7,
-	KEY_8,
-	KEY_9,
-	KEY_A,
-	KEY_APOSTROPHE,
-	KEY_B,
-	KEY_BACKSLASH,
-	KEY_C,
-	KEY_COMMA,
-	KEY_D,
-	KEY_DOT,
-	KEY_E,
-	KEY_ENTER,
-	KEY_EQUAL,
-	KEY_F,
-	KEY_G,
-	KEY_GRAVE,
-	KEY_H,
-	KEY_I,
-	KEY_J,
-	KEY_K,
-	KEY_L,
-	KEY_LEFTBRACE,
-	KEY_LEFTSHIFT,
-	KEY_M,
-	KEY_MINUS,
-	KEY_N,
-	KEY_O,
-	KEY_P,
-	KEY_Q,
-	KEY_R,
-	KEY_RIGHTBRACE,
-	KEY_S,
-	KEY_SEMICOLON,
-	KEY_SLASH,
-	KEY_SPACE,
-	KEY_T,
-	KEY_TAB,
-	KEY_U,
-	KEY_V,
-	KEY_W,
-	KEY_X,
-	KEY_Y,
-	KEY_Z,
-}
-
 // RuneEvents maps runes to the corresponding key event sequences for input simulation.
 var RuneEvents = map[rune][]Event{
 	// Lowercase letters
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/icholy/whisperd/internal/daemon"
+	"github.com/icholy/whisperd/internal/openai"
+)
+
+// Urgency levels defined by the notification specification.
+const (
+	Low      byte = 0
+	Normal   byte = 1
+	Critical byte = 2
+)
+
+// Notifier shows freedesktop desktop notifications for daemon events.
+type Notifier struct {
+	// Interval is the minimum time between two notifications with the same summary.
+	Interval time.Duration
+	// Transcripts enables a preview notification for every transcript.
+	Transcripts bool
+
+	mu        sync.Mutex
+	conn      *dbus.Conn
+	last      map[string]time.Time
+	previewID uint32 // id of the preview notification, replaced by the next one
+}
+
+// Connect connects to the session bus.
+func (n *Notifier) Connect() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.conn = conn
+	n.mu.Unlock()
+	return nil
+}
+
+// Close closes the session bus connection.
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	return n.conn.Close()
+}
+
+// HandleEvent shows a notification for failures and, if enabled, transcripts.
+// It is meant to be registered with daemon.Daemon.Listen.
+func (n *Notifier) HandleEvent(e daemon.Event) {
+	switch e.Kind {
+	case daemon.Failed:
+		go n.Notify(summary(e.Err), e.Err.Error(), Critical)
+	case daemon.Transcribed:
+		if n.Transcripts {
+			go n.preview(e.Text)
+		}
+	}
+}
+
+// summary returns a short description of a dictation error.
+func summary(err error) string {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return "whisperd: API authentication failed"
+	}
+	var derr *daemon.Error
+	if errors.As(err, &derr) {
+		switch derr.Op {
+		case "record":
+			return "whisperd: microphone unavailable"
+		case "input":
+			return "whisperd: input device disconnected"
+		case "transcribe":
+			return "whisperd: transcription failed"
+		case "emit":
+			return "whisperd: failed to type text"
+		}
+	}
+	return "whisperd: dictation failed"
+}
+
+// Notify shows a notification unless one with the same summary was shown within Interval.
+func (n *Notifier) Notify(summary, body string, urgency byte) error {
+	n.mu.Lock()
+	if n.last == nil {
+		n.last = map[string]time.Time{}
+	}
+	if t, ok := n.last[summary]; ok && time.Since(t) < n.Interval {
+		n.mu.Unlock()
+		return nil
+	}
+	n.last[summary] = time.Now()
+	n.mu.Unlock()
+	_, err := n.send(0, summary, body, urgency)
+	return err
+}
+
+// preview shows the transcript, replacing the previous preview.
+func (n *Notifier) preview(text string) error {
+	n.mu.Lock()
+	replaces := n.previewID
+	n.mu.Unlock()
+	id, err := n.send(replaces, "whisperd", text, Low)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.previewID = id
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *Notifier) send(replaces uint32, summary, body string, urgency byte) (uint32, error) {
+	n.mu.Lock()
+	conn := n.conn
+	n.mu.Unlock()
+	if conn == nil {
+		return 0, errors.New("notify: not connected")
+	}
+	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	var id uint32
+	err := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"whisperd",
+		replaces,
+		"audio-input-microphone",
+		summary,
+		body,
+		[]string{},
+		map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)},
+		int32(-1),
+	).Store(&id)
+	return id, err
+}
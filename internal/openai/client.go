@@ -9,58 +9,135 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+
+	"github.com/icholy/whisperd/internal/secret"
 )
 
 // Client is an OpenAI API client for audio transcription.
 type Client struct {
-	APIKey  string
+	APIKey  secret.Secret
 	BaseURL string
 }
 
-// Transcribe sends a WAV audio file to the OpenAI Whisper API and returns the transcribed text.
-func (c *Client) Transcribe(ctx context.Context, wav io.Reader) (string, error) {
+// TranscribeOptions are optional parameters for a transcription request.
+type TranscribeOptions struct {
+	Model    string // defaults to whisper-1
+	Language string // ISO-639-1 code, detected automatically if empty
+	Prompt   string // text to guide the model's style or vocabulary
+}
+
+// contentTypes are the MIME types of the supported audio file extensions.
+var contentTypes = map[string]string{
+	"wav":  "audio/wav",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+	"mp3":  "audio/mpeg",
+}
+
+// Transcribe sends an audio file in the format named by its extension, e.g.
+// "wav" or "flac", to the OpenAI Whisper API and returns the transcribed text.
+func (c *Client) Transcribe(ctx context.Context, format string, audio io.Reader, opt TranscribeOptions) (string, error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported audio format: %q", format)
+	}
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
-	fw, err := w.CreateFormFile("file", "audio.wav")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="audio.%s"`, format))
+	h.Set("Content-Type", contentType)
+	fw, err := w.CreatePart(h)
 	if err != nil {
 		return "", err
 	}
-	if _, err := io.Copy(fw, wav); err != nil {
+	if _, err := io.Copy(fw, audio); err != nil {
 		return "", err
 	}
-	w.WriteField("model", "whisper-1")
+	w.WriteField("model", cmp.Or(opt.Model, "whisper-1"))
+	if opt.Language != "" {
+		w.WriteField("language", opt.Language)
+	}
+	if opt.Prompt != "" {
+		w.WriteField("prompt", opt.Prompt)
+	}
 	if err := w.Close(); err != nil {
 		return "", err
 	}
-	baseURL := cmp.Or(c.BaseURL, "https://api.openai.com/v1")
-	transcriptionsURL, err := url.JoinPath(baseURL, "/audio/transcriptions")
-	if err != nil {
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := c.post(ctx, "/audio/transcriptions", w.FormDataContentType(), &buf, &out); err != nil {
 		return "", err
 	}
-	req, err := http.NewRequestWithContext(ctx, "POST", transcriptionsURL, &buf)
+	return out.Text, nil
+}
+
+// ChatMessage is a single message in a chat completion request.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Chat sends the messages to the chat completions API and returns the content of the reply.
+func (c *Client) Chat(ctx context.Context, model string, messages []ChatMessage) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":    model,
+		"messages": messages,
+	})
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	var out struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := c.post(ctx, "/chat/completions", "application/json", bytes.NewReader(body), &out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("openai: no choices in response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("openai: %s: %s", e.Status, e.Body)
+}
+
+// post sends an authenticated request to the API endpoint at path and decodes the JSON response into out.
+func (c *Client) post(ctx context.Context, path, contentType string, body io.Reader, out any) error {
+	baseURL := cmp.Or(c.BaseURL, "https://api.openai.com/v1")
+	endpoint, err := url.JoinPath(baseURL, path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+string(c.APIKey))
+	req.Header.Set("Content-Type", contentType)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return "", fmt.Errorf("failed to read error body: %w", err)
+			return fmt.Errorf("failed to read error body: %w", err)
 		}
-		return "", fmt.Errorf("openai: %s: %s", resp.Status, string(body))
-	}
-	var out struct {
-		Text string `json:"text"`
+		return &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", err
-	}
-	return out.Text, nil
+	return json.NewDecoder(resp.Body).Decode(out)
 }
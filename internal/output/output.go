@@ -0,0 +1,94 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/icholy/whisperd/internal/inputcodes"
+	"github.com/icholy/whisperd/internal/uinput"
+	"github.com/icholy/whisperd/internal/voicecmd"
+)
+
+// Sink receives the final text of a dictation.
+type Sink interface {
+	Emit(ctx context.Context, text string) error
+}
+
+// Keyboard types text on a uinput device, pressing the keys for any voice
+// commands it contains.
+type Keyboard struct {
+	Device   *os.File
+	Commands voicecmd.Table
+}
+
+// Emit types the text.
+func (k *Keyboard) Emit(ctx context.Context, text string) error {
+	for _, seg := range k.Commands.Apply(text) {
+		if seg.Text != "" {
+			if err := uinput.EmitText(k.Device, seg.Text); err != nil {
+				return fmt.Errorf("emit text: %w", err)
+			}
+		}
+		for _, chord := range seg.Chords {
+			// give the target window a chance to process the previous keys
+			time.Sleep(10 * time.Millisecond)
+			if err := uinput.EmitChord(k.Device, chord); err != nil {
+				return fmt.Errorf("emit keys: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Clipboard copies text to the clipboard using wl-copy on Wayland or xclip on X11.
+type Clipboard struct{}
+
+// Emit copies the text to the clipboard.
+func (Clipboard) Emit(ctx context.Context, text string) error {
+	return Copy(ctx, text)
+}
+
+// Copy copies text to the clipboard.
+func Copy(ctx context.Context, text string) error {
+	var cmd *exec.Cmd
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmd = exec.CommandContext(ctx, "wl-copy")
+	} else {
+		cmd = exec.CommandContext(ctx, "xclip", "-selection", "clipboard")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("copy to clipboard: %w", err)
+	}
+	return nil
+}
+
+// Paste copies text to the clipboard and presses Ctrl+V on a uinput device.
+// This is much faster than typing long transcripts and supports any character.
+type Paste struct {
+	Device *os.File
+	// Chord is the paste shortcut. It defaults to Ctrl+V.
+	Chord []uint16
+}
+
+// Emit pastes the text.
+func (p *Paste) Emit(ctx context.Context, text string) error {
+	if err := Copy(ctx, text); err != nil {
+		return err
+	}
+	chord := p.Chord
+	if len(chord) == 0 {
+		chord = []uint16{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_V}
+	}
+	// give the clipboard owner time to take ownership of the selection
+	time.Sleep(50 * time.Millisecond)
+	if err := uinput.EmitChord(p.Device, chord); err != nil {
+		return fmt.Errorf("emit keys: %w", err)
+	}
+	return nil
+}
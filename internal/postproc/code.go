@@ -0,0 +1,210 @@
+package postproc
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Code converts dictated code into source text. Spoken casing commands turn
+// the words that follow them into an identifier, and spoken symbol names are
+// replaced with the symbol:
+//
+//	"camel case user id"    -> userId
+//	"snake case max retries" -> max_retries
+//	"open paren"            -> (
+//	"arrow"                 -> ->
+//
+// A casing command consumes words up to the next command or up to the end of the
+// sentence as punctuated by Whisper. Other words are lowercased and Whisper's own
+// punctuation is dropped.
+type Code struct{}
+
+// symbol is the text for a spoken symbol, and whether it attaches to the
+// previous or next piece without a space.
+type symbol struct {
+	text        string
+	left, right bool
+}
+
+var codeSymbols = map[string]symbol{
+	"open paren":            {"(", true, true},
+	"close paren":           {")", true, false},
+	"open bracket":          {"[", true, true},
+	"close bracket":         {"]", true, false},
+	"open brace":            {"{", false, true},
+	"close brace":           {"}", false, false},
+	"open angle":            {"<", true, true},
+	"close angle":           {">", true, false},
+	"arrow":                 {"->", true, true},
+	"fat arrow":             {"=>", false, false},
+	"left arrow":            {"<-", false, false},
+	"dot":                   {".", true, true},
+	"comma":                 {",", true, false},
+	"colon":                 {":", true, false},
+	"semicolon":             {";", true, false},
+	"equals":                {"=", false, false},
+	"double equals":         {"==", false, false},
+	"triple equals":         {"===", false, false},
+	"not equals":            {"!=", false, false},
+	"colon equals":          {":=", false, false},
+	"plus":                  {"+", false, false},
+	"plus plus":             {"++", true, false},
+	"plus equals":           {"+=", false, false},
+	"minus":                 {"-", false, false},
+	"minus minus":           {"--", true, false},
+	"minus equals":          {"-=", false, false},
+	"times":                 {"*", false, false},
+	"star":                  {"*", false, true},
+	"divided by":            {"/", false, false},
+	"slash":                 {"/", true, true},
+	"backslash":             {"\\", true, true},
+	"percent":               {"%", false, false},
+	"less than":             {"<", false, false},
+	"greater than":          {">", false, false},
+	"less than or equal":    {"<=", false, false},
+	"greater than or equal": {">=", false, false},
+	"and and":               {"&&", false, false},
+	"or or":                 {"||", false, false},
+	"ampersand":             {"&", false, true},
+	"pipe":                  {"|", false, false},
+	"bang":                  {"!", false, true},
+	"question mark":         {"?", true, false},
+	"hash":                  {"#", false, true},
+	"dollar":                {"$", false, true},
+	"at sign":               {"@", false, true},
+	"caret":                 {"^", false, false},
+	"tilde":                 {"~", false, true},
+	"underscore":            {"_", true, true},
+	"backtick":              {"`", false, false},
+	"quote":                 {`"`, false, false},
+	"single quote":          {"'", false, false},
+	"space":                 {" ", true, true},
+}
+
+// codeCasings maps spoken casing commands to functions joining lowercase words.
+var codeCasings = map[string]func(words []string) string{
+	"camel case": func(words []string) string {
+		for i := 1; i < len(words); i++ {
+			words[i] = upperFirst(words[i])
+		}
+		return strings.Join(words, "")
+	},
+	"pascal case": func(words []string) string {
+		for i := range words {
+			words[i] = upperFirst(words[i])
+		}
+		return strings.Join(words, "")
+	},
+	"snake case": func(words []string) string {
+		return strings.Join(words, "_")
+	},
+	"kebab case": func(words []string) string {
+		return strings.Join(words, "-")
+	},
+	"constant case": func(words []string) string {
+		return strings.ToUpper(strings.Join(words, "_"))
+	},
+	"screaming snake case": func(words []string) string {
+		return strings.ToUpper(strings.Join(words, "_"))
+	},
+	"all caps": func(words []string) string {
+		return strings.ToUpper(strings.Join(words, ""))
+	},
+	"flat case": func(words []string) string {
+		return strings.Join(words, "")
+	},
+}
+
+// codeWord is a lowercased spoken word and whether Whisper ended a sentence or
+// clause after it.
+type codeWord struct {
+	text string
+	stop bool
+}
+
+// Process converts the dictated text.
+func (Code) Process(ctx context.Context, text string) (string, error) {
+	var words []codeWord
+	for _, f := range strings.Fields(text) {
+		w := strings.ToLower(strings.TrimFunc(f, unicode.IsPunct))
+		if w == "" {
+			continue
+		}
+		last := f[len(f)-1]
+		words = append(words, codeWord{text: w, stop: strings.IndexByte(".,;:!?", last) >= 0})
+	}
+	var pieces []symbol
+	quoted := map[string]bool{}
+	for i := 0; i < len(words); {
+		if phrase, n := matchPhrase(words[i:], codeCasings); n > 0 {
+			i += n
+			var ident []string
+			for i < len(words) && !isCodeCommand(words[i:]) {
+				ident = append(ident, words[i].text)
+				i++
+				if words[i-1].stop {
+					break
+				}
+			}
+			if len(ident) > 0 {
+				pieces = append(pieces, symbol{text: codeCasings[phrase](ident)})
+			}
+			continue
+		}
+		if phrase, n := matchPhrase(words[i:], codeSymbols); n > 0 {
+			sym := codeSymbols[phrase]
+			if sym.text == `"` || sym.text == "'" || sym.text == "`" {
+				// opening quotes attach to the right, closing quotes to the left
+				sym.right = !quoted[sym.text]
+				sym.left = quoted[sym.text]
+				quoted[sym.text] = !quoted[sym.text]
+			}
+			pieces = append(pieces, sym)
+			i += n
+			continue
+		}
+		pieces = append(pieces, symbol{text: words[i].text})
+		i++
+	}
+	var b strings.Builder
+	for i, p := range pieces {
+		if i > 0 && !p.left && !pieces[i-1].right {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p.text)
+	}
+	return b.String(), nil
+}
+
+// matchPhrase returns the longest key of phrases matching the start of words
+// and the number of words it spans.
+func matchPhrase[V any](words []codeWord, phrases map[string]V) (string, int) {
+	var best string
+	var bestN int
+	for phrase := range phrases {
+		pw := strings.Fields(phrase)
+		if len(pw) <= bestN || len(pw) > len(words) {
+			continue
+		}
+		ok := true
+		for i, w := range pw {
+			if words[i].text != w || (words[i].stop && i < len(pw)-1) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			best, bestN = phrase, len(pw)
+		}
+	}
+	return best, bestN
+}
+
+func isCodeCommand(words []codeWord) bool {
+	if _, n := matchPhrase(words, codeCasings); n > 0 {
+		return true
+	}
+	_, n := matchPhrase(words, codeSymbols)
+	return n > 0
+}
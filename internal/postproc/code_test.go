@@ -0,0 +1,52 @@
+package postproc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"camel case user id", "userId"},
+		{"Snake case max retries.", "max_retries"},
+		{"open paren", "("},
+		{"arrow", "->"},
+		{"pascal case http server", "HttpServer"},
+		{"kebab case dry run", "dry-run"},
+		{"constant case max retries", "MAX_RETRIES"},
+		{"screaming snake case max retries", "MAX_RETRIES"},
+		// a casing command ends at the next command or sentence
+		{"camel case user id equals camel case new user", "userId = newUser"},
+		{"Camel case user id. Snake case max retries.", "userId max_retries"},
+		{"func main open paren close paren open brace", "func main() {"},
+		{"p arrow next", "p->next"},
+		{"x colon equals y plus plus", "x := y++"},
+		{"a fat arrow b", "a => b"},
+		// the longest phrase wins
+		{"a less than or equal b", "a <= b"},
+		// opening quotes attach to the next word, closing quotes to the previous
+		{"quote hello world quote", `"hello world"`},
+		{"print open paren quote hi quote close paren", `print("hi")`},
+		{"single quote a single quote plus single quote b single quote", `'a' + 'b'`},
+		{"quote a quote quote b quote", `"a" "b"`},
+		{"backtick ls backtick", "`ls`"},
+		// an unterminated quote
+		{"quote hello", `"hello`},
+		// a stop inside a phrase doesn't match it
+		{"open. Paren", "open paren"},
+		{"camel case", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := Code{}.Process(context.Background(), tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package postproc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/icholy/whisperd/internal/openai"
+)
+
+// Prompts are built-in system prompts which can be referred to by name.
+var Prompts = map[string]string{
+	"grammar": "You clean up dictated text. Fix grammar, spelling and punctuation " +
+		"without changing the meaning or adding content. Reply with the corrected text only.",
+	"bullets": "You format dictated text. Rewrite it as a markdown bullet list with one item per point. " +
+		"Reply with the list only.",
+	"email": "You format dictated text as the body of an email with a greeting, paragraphs and a sign-off. " +
+		"Do not add content that was not dictated. Reply with the email body only.",
+	"translate": "You translate dictated text to English. Reply with the translation only.",
+}
+
+// LLM rewrites text using a chat completions model.
+// If the request fails, takes longer than Timeout, or the reply is empty,
+// the text is returned unchanged.
+type LLM struct {
+	Client  *openai.Client
+	Model   string
+	Prompt  string // system prompt or the name of one of the Prompts
+	Timeout time.Duration
+	Log     *slog.Logger
+}
+
+// Process sends the text to the model and returns its reply.
+func (l *LLM) Process(ctx context.Context, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	prompt := l.Prompt
+	if p, ok := Prompts[prompt]; ok {
+		prompt = p
+	}
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	out, err := l.Client.Chat(ctx, l.Model, []openai.ChatMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: text},
+	})
+	if err != nil {
+		l.logger().Warn("llm cleanup failed, using raw text", "error", err)
+		return text, nil
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		l.logger().Warn("llm cleanup returned nothing, using raw text")
+		return text, nil
+	}
+	l.logger().Info("llm cleanup", "model", l.Model, "duration", time.Since(start))
+	return out, nil
+}
+
+func (l *LLM) logger() *slog.Logger {
+	if l.Log != nil {
+		return l.Log
+	}
+	return slog.Default()
+}
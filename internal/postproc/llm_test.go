@@ -0,0 +1,48 @@
+package postproc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/icholy/whisperd/internal/openai"
+)
+
+func TestLLM(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reply  string
+		want   string
+	}{
+		{name: "reply", status: http.StatusOK, reply: " Hello, world. ", want: "Hello, world."},
+		{name: "empty reply", status: http.StatusOK, reply: " \n ", want: "hello world"},
+		{name: "error", status: http.StatusInternalServerError, want: "hello world"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]any{
+					"choices": []any{map[string]any{"message": map[string]string{"role": "assistant", "content": tt.reply}}},
+				})
+			}))
+			defer srv.Close()
+			l := &LLM{
+				Client: &openai.Client{APIKey: "test", BaseURL: srv.URL},
+				Prompt: "grammar",
+				Log:    slog.New(slog.DiscardHandler),
+			}
+			got, err := l.Process(context.Background(), "hello world")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
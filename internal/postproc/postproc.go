@@ -0,0 +1,46 @@
+package postproc
+
+import "context"
+
+// Processor transforms transcribed text before it is emitted.
+type Processor interface {
+	Process(ctx context.Context, text string) (string, error)
+}
+
+// Func adapts a plain function to the Processor interface.
+type Func func(text string) string
+
+// Process calls f(text).
+func (f Func) Process(ctx context.Context, text string) (string, error) {
+	return f(text), nil
+}
+
+// Committer is implemented by processors which depend on the previously
+// emitted text. Commit is called with the text once it has been emitted.
+type Committer interface {
+	Commit(text string)
+}
+
+// Chain runs a list of processors in order.
+type Chain []Processor
+
+// Process passes text through each processor in the chain.
+func (c Chain) Process(ctx context.Context, text string) (string, error) {
+	for _, p := range c {
+		var err error
+		text, err = p.Process(ctx, text)
+		if err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// Commit passes the emitted text to the processors which implement Committer.
+func (c Chain) Commit(text string) {
+	for _, p := range c {
+		if cm, ok := p.(Committer); ok {
+			cm.Commit(text)
+		}
+	}
+}
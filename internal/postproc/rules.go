@@ -0,0 +1,202 @@
+package postproc
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Rule describes a single text transform. The Type field selects the transform
+// and determines which of the other fields are used:
+//
+//   - "trim": removes surrounding whitespace and any of Chars.
+//   - "regex": replaces matches of Pattern with Replace (see regexp.Regexp.ReplaceAllString).
+//   - "dictionary": replaces each phrase in Terms, matched case insensitively on word boundaries.
+//   - "fillers": removes filler words. Words defaults to DefaultFillers.
+//   - "case": converts the text to Case, one of "lower", "upper", "title" or "sentence".
+//   - "code": applies the code dictation grammar (see Code).
+type Rule struct {
+	Type    string            `toml:"type"`
+	Chars   string            `toml:"chars"`
+	Pattern string            `toml:"pattern"`
+	Replace string            `toml:"replace"`
+	Terms   map[string]string `toml:"terms"`
+	Words   []string          `toml:"words"`
+	Case    string            `toml:"case"`
+}
+
+// DefaultFillers is the list of words removed by the "fillers" rule.
+var DefaultFillers = []string{"um", "umm", "uh", "uhh", "er", "erm", "ah", "hmm"}
+
+// Processor compiles the rule into a Processor.
+func (r Rule) Processor() (Processor, error) {
+	switch r.Type {
+	case "trim":
+		return Func(func(text string) string {
+			return strings.Trim(strings.TrimSpace(text), r.Chars)
+		}), nil
+	case "regex":
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return Func(func(text string) string {
+			return re.ReplaceAllString(text, r.Replace)
+		}), nil
+	case "dictionary":
+		return dictionary(r.Terms)
+	case "fillers":
+		if len(r.Words) == 0 {
+			return fillers(DefaultFillers), nil
+		}
+		return fillers(r.Words), nil
+	case "case":
+		return caseConversion(r.Case)
+	case "code":
+		return Code{}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule type: %q", r.Type)
+	}
+}
+
+// Compile compiles a list of rules into a Chain.
+func Compile(rules []Rule) (Chain, error) {
+	var c Chain
+	for i, r := range rules {
+		p, err := r.Processor()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		c = append(c, p)
+	}
+	return c, nil
+}
+
+// LoadRules reads and compiles a TOML rules file. The file contains a list of
+// [[rule]] tables.
+func LoadRules(path string) (Chain, error) {
+	var file struct {
+		Rules []Rule `toml:"rule"`
+	}
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, err
+	}
+	c, err := Compile(file.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func dictionary(terms map[string]string) (Processor, error) {
+	type replacement struct {
+		re   *regexp.Regexp
+		with string
+	}
+	// replace longer phrases first so they win over their substrings
+	phrases := slices.SortedFunc(maps.Keys(terms), func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	var rr []replacement
+	for _, phrase := range phrases {
+		words := strings.Fields(phrase)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("empty dictionary term")
+		}
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		// \b only matches next to a word character, so terms like "c++"
+		// are only anchored on the edges where they have one
+		pattern := strings.Join(words, `\s+`)
+		if r, _ := utf8.DecodeRuneInString(phrase); isWordChar(r) {
+			pattern = `\b` + pattern
+		}
+		if r, _ := utf8.DecodeLastRuneInString(phrase); isWordChar(r) {
+			pattern += `\b`
+		}
+		re, err := regexp.Compile(`(?i)` + pattern)
+		if err != nil {
+			return nil, err
+		}
+		rr = append(rr, replacement{re: re, with: terms[phrase]})
+	}
+	return Func(func(text string) string {
+		for _, r := range rr {
+			text = r.re.ReplaceAllLiteralString(text, r.with)
+		}
+		return text
+	}), nil
+}
+
+// isWordChar reports whether r is matched by \w.
+func isWordChar(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// wordPattern matches a word with its surrounding punctuation.
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// blanks matches spaces and tabs, but not newlines.
+var blanks = regexp.MustCompile(`^[ \t]+`)
+
+func fillers(words []string) Processor {
+	set := map[string]bool{}
+	for _, w := range words {
+		set[strings.ToLower(w)] = true
+	}
+	return Func(func(text string) string {
+		// remove the fillers in place with the spaces after them, or before
+		// them at the end of a line, so that line breaks are kept
+		var b strings.Builder
+		last := 0
+		for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if !set[strings.ToLower(strings.TrimFunc(text[start:end], unicode.IsPunct))] {
+				continue
+			}
+			end += len(blanks.FindString(text[end:]))
+			if end == len(text) || text[end] == '\n' {
+				start = last + len(strings.TrimRight(text[last:start], " \t"))
+			}
+			b.WriteString(text[last:start])
+			last = end
+		}
+		b.WriteString(text[last:])
+		out := b.String()
+		// keep the sentence capitalized if a leading filler was removed
+		if r, _ := utf8.DecodeRuneInString(text); unicode.IsUpper(r) && out != "" {
+			out = upperFirst(out)
+		}
+		return out
+	})
+}
+
+func caseConversion(c string) (Processor, error) {
+	switch c {
+	case "lower":
+		return Func(strings.ToLower), nil
+	case "upper":
+		return Func(strings.ToUpper), nil
+	case "title":
+		return Func(func(text string) string {
+			return wordPattern.ReplaceAllStringFunc(text, upperFirst)
+		}), nil
+	case "sentence":
+		return Func(func(text string) string {
+			if text == "" {
+				return text
+			}
+			return upperFirst(text)
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown case: %q", c)
+	}
+}
@@ -0,0 +1,116 @@
+package postproc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		in   string
+		want string
+	}{
+		{
+			name: "trim",
+			rule: Rule{Type: "trim", Chars: "."},
+			in:   "  hello.  ",
+			want: "hello",
+		},
+		{
+			name: "regex",
+			rule: Rule{Type: "regex", Pattern: `(\d+) percent`, Replace: "$1%"},
+			in:   "up 5 percent",
+			want: "up 5%",
+		},
+		{
+			name: "dictionary",
+			rule: Rule{Type: "dictionary", Terms: map[string]string{"cube control": "kubectl"}},
+			in:   "run Cube  Control get pods",
+			want: "run kubectl get pods",
+		},
+		{
+			name: "dictionary word boundaries",
+			rule: Rule{Type: "dictionary", Terms: map[string]string{"go": "Go"}},
+			in:   "go and gopher",
+			want: "Go and gopher",
+		},
+		{
+			name: "dictionary longest first",
+			rule: Rule{Type: "dictionary", Terms: map[string]string{"post": "POST", "post gres": "Postgres"}},
+			in:   "post gres and post",
+			want: "Postgres and POST",
+		},
+		{
+			name: "dictionary punctuation edges",
+			rule: Rule{Type: "dictionary", Terms: map[string]string{"c++": "C++", ".net": ".NET"}},
+			in:   "I write c++ and .net code",
+			want: "I write C++ and .NET code",
+		},
+		{
+			name: "fillers",
+			rule: Rule{Type: "fillers"},
+			in:   "Um, I think, uh, that works umm",
+			want: "I think, that works",
+		},
+		{
+			name: "fillers keep newlines",
+			rule: Rule{Type: "fillers"},
+			in:   "first line um\num second line\n\nthird  line",
+			want: "first line\nsecond line\n\nthird  line",
+		},
+		{
+			name: "fillers custom words",
+			rule: Rule{Type: "fillers", Words: []string{"like"}},
+			in:   "it was like great um",
+			want: "it was great um",
+		},
+		{
+			name: "title case",
+			rule: Rule{Type: "case", Case: "title"},
+			in:   "hello world\nsecond  line",
+			want: "Hello World\nSecond  Line",
+		},
+		{
+			name: "sentence case",
+			rule: Rule{Type: "case", Case: "sentence"},
+			in:   "hello world",
+			want: "Hello world",
+		},
+		{
+			name: "upper case",
+			rule: Rule{Type: "case", Case: "upper"},
+			in:   "hello",
+			want: "HELLO",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.rule.Processor()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Process(context.Background(), tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	for _, r := range []Rule{
+		{Type: "nope"},
+		{Type: "regex", Pattern: "("},
+		{Type: "case", Case: "nope"},
+		{Type: "dictionary", Terms: map[string]string{" ": "x"}},
+	} {
+		if _, err := r.Processor(); err == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}
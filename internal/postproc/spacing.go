@@ -0,0 +1,132 @@
+package postproc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Spacing joins consecutive dictations into running text. It remembers the
+// previously emitted text and, when the next dictation follows within Window,
+// inserts a separating space and adjusts the case of the first letter to match
+// the end of the previous sentence. The daemon calls Commit once the text has
+// been emitted, so a failed or cancelled dictation isn't joined to.
+type Spacing struct {
+	// Window is how long the previous dictation is remembered.
+	Window time.Duration
+	// StripPeriod removes the trailing period from dictations with at most
+	// this many words. Zero disables stripping.
+	StripPeriod int
+
+	mu       sync.Mutex
+	last     string
+	lastTime time.Time
+}
+
+// Update changes the options without forgetting the previous dictation,
+// which is used when the configuration is reloaded.
+func (s *Spacing) Update(window time.Duration, stripPeriod int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Window = window
+	s.StripPeriod = stripPeriod
+}
+
+// Process adjusts text relative to the previously committed dictation.
+func (s *Spacing) Process(ctx context.Context, text string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return text, nil
+	}
+	if s.StripPeriod > 0 && len(strings.Fields(text)) <= s.StripPeriod {
+		if t, ok := strings.CutSuffix(text, "."); ok && !strings.HasSuffix(t, ".") {
+			text = t
+		}
+	}
+	if s.last != "" && time.Since(s.lastTime) < s.Window {
+		prev, _ := utf8.DecodeLastRuneInString(s.last)
+		first, _ := utf8.DecodeRuneInString(text)
+		if sentenceEnd(s.last) {
+			text = upperFirst(text)
+		} else {
+			text = lowerFirst(text)
+		}
+		if !unicode.IsSpace(prev) && !strings.ContainsRune(".,;:!?)]}", first) {
+			text = " " + text
+		}
+	}
+	return text, nil
+}
+
+// Commit implements Committer, remembering the emitted text.
+func (s *Spacing) Commit(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if text == "" {
+		return
+	}
+	s.last = text
+	s.lastTime = time.Now()
+}
+
+// sentenceEnd reports whether text ends a sentence, ignoring closing quotes and brackets.
+func sentenceEnd(text string) bool {
+	text = strings.TrimRightFunc(text, func(r rune) bool {
+		// a trailing newline ends the sentence
+		return r == ' ' || r == '\t' || strings.ContainsRune(`"')]}`, r)
+	})
+	if text == "" {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(".!?\n", last)
+}
+
+func upperFirst(text string) string {
+	r, n := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(r)) + text[n:]
+}
+
+// lowerFirst lowercases the first letter if the first word is a common
+// word. Other words may be names like "Bob" or "Kubernetes", so their case
+// is left alone.
+func lowerFirst(text string) string {
+	word, _, _ := strings.Cut(text, " ")
+	word = strings.TrimRightFunc(word, unicode.IsPunct)
+	// acronyms like "IT" are kept too
+	acronym := utf8.RuneCountInString(word) > 1 && word == strings.ToUpper(word)
+	if acronym || !commonWords[strings.ToLower(word)] {
+		return text
+	}
+	r, n := utf8.DecodeRuneInString(text)
+	return string(unicode.ToLower(r)) + text[n:]
+}
+
+// commonWords are the words which Whisper capitalizes at the start of a
+// dictation that continues a sentence.
+var commonWords = wordSet(`
+	a an the and or but nor so yet then than because if unless when whenever
+	while where which who whom whose what that this these those there here
+	it its it's he she we they you me him her us them my your our their his
+	is are was were be been being am do does did have has had will would
+	can could should shall may might must not no yes also just only even
+	to of in on at for with from by as into onto about after before since
+	until over under between through during without within like
+	all any some each every more most many much other another such
+	how why maybe perhaps probably however therefore still again
+	now later today tomorrow yesterday please thanks okay ok well
+`)
+
+// wordSet returns the set of the whitespace separated words.
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
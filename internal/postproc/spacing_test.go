@@ -0,0 +1,82 @@
+package postproc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSpacing(t *testing.T) {
+	tests := []struct {
+		name        string
+		prev        string // committed before the text, empty for none
+		expired     bool
+		stripPeriod int
+		text        string
+		want        string
+	}{
+		{name: "first dictation", text: "hello there.", want: "hello there."},
+		{name: "leading space", prev: "Hello.", text: "How are you?", want: " How are you?"},
+		{name: "no space before a comma", prev: "Hello", text: ", world", want: ", world"},
+		{name: "no space before a period", prev: "the end", text: ".", want: "."},
+		{name: "no space before a question mark", prev: "really", text: "?", want: "?"},
+		{name: "no space before a closing paren", prev: "(see below", text: ")", want: ")"},
+		{name: "capitalize after a period", prev: "It works.", text: "and then it broke", want: " And then it broke"},
+		{name: "capitalize after a closing quote", prev: `He said "stop."`, text: "then he left", want: " Then he left"},
+		{name: "capitalize after a newline", prev: "Dear Bob,\n", text: "thanks", want: "Thanks"},
+		{name: "lowercase a common word", prev: "I think", text: "The answer is yes", want: " the answer is yes"},
+		{name: "keep a name", prev: "I talked to", text: "Bob yesterday", want: " Bob yesterday"},
+		{name: "keep a place", prev: "we flew to", text: "Paris.", want: " Paris."},
+		{name: "keep an unknown word", prev: "we deploy on", text: "Kubernetes", want: " Kubernetes"},
+		{name: "keep I", prev: "and then", text: "I left", want: " I left"},
+		{name: "keep an acronym", prev: "call the", text: "IT department", want: " IT department"},
+		{name: "window expired", prev: "Hello.", expired: true, text: "how are you", want: "how are you"},
+		{name: "strip period", stripPeriod: 3, text: "Okay.", want: "Okay"},
+		{name: "keep ellipsis", stripPeriod: 3, text: "Wait...", want: "Wait..."},
+		{name: "keep period of long dictations", stripPeriod: 3, text: "This has more words.", want: "This has more words."},
+		{name: "stripped and joined", prev: "Sounds good.", stripPeriod: 3, text: "thanks.", want: " Thanks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Spacing{Window: time.Minute, StripPeriod: tt.stripPeriod}
+			if tt.prev != "" {
+				s.Commit(tt.prev)
+			}
+			if tt.expired {
+				s.lastTime = time.Now().Add(-2 * s.Window)
+			}
+			got, err := s.Process(context.Background(), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpacingCommit(t *testing.T) {
+	s := &Spacing{Window: time.Minute}
+	chain := Chain{Func(func(text string) string { return text }), s}
+	ctx := context.Background()
+	// a dictation which was never emitted isn't joined to
+	if _, err := chain.Process(ctx, "Hello."); err != nil {
+		t.Fatal(err)
+	}
+	got, err := chain.Process(ctx, "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "world" {
+		t.Errorf("got %q after an uncommitted dictation, want %q", got, "world")
+	}
+	chain.Commit("Hello.")
+	got, err = chain.Process(ctx, "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != " World" {
+		t.Errorf("got %q after a commit, want %q", got, " World")
+	}
+}
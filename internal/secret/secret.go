@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Secret is a string which is redacted when printed or logged.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+// String implements fmt.Stringer.
+func (Secret) String() string { return redacted }
+
+// GoString implements fmt.GoStringer.
+func (Secret) GoString() string { return redacted }
+
+// LogValue implements slog.LogValuer.
+func (Secret) LogValue() slog.Value { return slog.StringValue(redacted) }
+
+// MarshalText implements encoding.TextMarshaler so the secret isn't leaked by encoders.
+func (Secret) MarshalText() ([]byte, error) { return []byte(redacted), nil }
+
+// ReadFile reads a secret from the file at path. The file may contain the
+// secret on its own, or be an environment file with a NAME=value line for the
+// given variable name. Relative paths are resolved against the systemd
+// $CREDENTIALS_DIRECTORY when it is set.
+func ReadFile(path, name string) (Secret, error) {
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "export ")
+		if value, ok := strings.CutPrefix(line, name+"="); ok {
+			return Secret(strings.Trim(value, `"'`)), nil
+		}
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "" {
+		return "", errors.New("empty secret file: " + path)
+	}
+	if strings.ContainsAny(s, "\n=") {
+		return "", errors.New("no " + name + " found in " + path)
+	}
+	return Secret(s), nil
+}
+
+// Credential returns the systemd credential with the given name, as passed with
+// LoadCredential=. It returns false if there is no such credential.
+func Credential(name string) (Secret, bool) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", false
+	}
+	s := strings.TrimSpace(string(data))
+	return Secret(s), s != ""
+}
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("sk-very-secret")
+	text, err := s.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("key", "key", s)
+	data, err := json.Marshal(struct{ Key Secret }{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"String", s.String(), redacted},
+		{"GoString", s.GoString(), redacted},
+		{"LogValue", s.LogValue().String(), redacted},
+		{"MarshalText", string(text), redacted},
+		{"%v", fmt.Sprintf("%v", s), redacted},
+		{"%s", fmt.Sprintf("%s", s), redacted},
+		{"%#v", fmt.Sprintf("%#v", s), redacted},
+		{"%+v in a struct", fmt.Sprintf("%+v", struct{ Key Secret }{s}), "{Key:" + redacted + "}"},
+		{"%#v in a struct", fmt.Sprintf("%#v", struct{ Key Secret }{s}), "struct { Key secret.Secret }{Key:" + redacted + "}"},
+		{"json", string(data), `{"Key":"` + redacted + `"}`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if strings.Contains(logged.String(), string(s)) || !strings.Contains(logged.String(), "key="+redacted) {
+		t.Errorf("slog: got %q", logged.String())
+	}
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName = "org.freedesktop.secrets"
+	servicePath = "/org/freedesktop/secrets"
+	serviceIfc  = "org.freedesktop.Secret.Service"
+)
+
+// serviceSecret is the Secret struct defined by the Secret Service API.
+type serviceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// ErrNotFound is returned by Lookup when no item matches the attributes.
+var ErrNotFound = errors.New("secret not found")
+
+// Lookup returns the secret of the first item in the freedesktop Secret Service
+// (GNOME Keyring, KWallet) matching the attributes. Items stored with
+// secret-tool can be found using the same attributes:
+//
+//	secret-tool store --label=whisperd application whisperd
+func Lookup(ctx context.Context, attrs map[string]string) (Secret, error) {
+	conn, err := dbus.ConnectSessionBus(dbus.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("secret service: %w", err)
+	}
+	defer conn.Close()
+	svc := conn.Object(serviceName, servicePath)
+	var unlocked, locked []dbus.ObjectPath
+	if err := svc.CallWithContext(ctx, serviceIfc+".SearchItems", 0, attrs).Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("secret service: search items: %w", err)
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		unlocked, err = unlock(ctx, conn, svc, locked[:1])
+		if err != nil {
+			return "", fmt.Errorf("secret service: unlock: %w", err)
+		}
+	}
+	if len(unlocked) == 0 {
+		return "", ErrNotFound
+	}
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := svc.CallWithContext(ctx, serviceIfc+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("secret service: open session: %w", err)
+	}
+	defer conn.Object(serviceName, session).CallWithContext(ctx, "org.freedesktop.Secret.Session.Close", 0)
+	var secrets map[dbus.ObjectPath]serviceSecret
+	if err := svc.CallWithContext(ctx, serviceIfc+".GetSecrets", 0, unlocked[:1], session).Store(&secrets); err != nil {
+		return "", fmt.Errorf("secret service: get secrets: %w", err)
+	}
+	s, ok := secrets[unlocked[0]]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return Secret(s.Value), nil
+}
+
+// unlock unlocks the items, waiting for the user to complete the unlock prompt if one is required.
+func unlock(ctx context.Context, conn *dbus.Conn, svc dbus.BusObject, items []dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := svc.CallWithContext(ctx, serviceIfc+".Unlock", 0, items).Store(&unlocked, &prompt); err != nil {
+		return nil, err
+	}
+	if prompt == "/" {
+		return unlocked, nil
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface("org.freedesktop.Secret.Prompt"),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+	if err := conn.Object(serviceName, prompt).CallWithContext(ctx, "org.freedesktop.Secret.Prompt.Prompt", 0, "").Err; err != nil {
+		return nil, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case sig := <-signals:
+			if sig.Path != prompt || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return nil, errors.New("prompt dismissed")
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			paths, _ := result.Value().([]dbus.ObjectPath)
+			return paths, nil
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package tray
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Choice is an entry in one of the selection submenus.
+type Choice struct {
+	Value string
+	Label string
+}
+
+// maxChoices is the number of entries in each submenu. The menu libraries
+// can't reliably remove items, so the entries are created up front and
+// hidden when they're not used.
+const maxChoices = 10
+
+// menuItem is implemented by the MenuItem types of both systray libraries.
+type menuItem interface {
+	SetTitle(title string)
+	Show()
+	Hide()
+	Check()
+	Uncheck()
+	Enable()
+	Disable()
+}
+
+// item is a menu item created by one of the systray backends.
+type item struct {
+	menuItem
+	clicked <-chan struct{}
+	// addSub adds a checkbox to the item's submenu
+	addSub func(title, tooltip string) *item
+}
+
+// choices is a submenu of checkboxes of which at most one is checked.
+type choices struct {
+	kind     ActionKind
+	parent   *item
+	items    []*item
+	values   []Choice
+	selected string
+}
+
+var menu struct {
+	sync.Mutex
+	ready     bool
+	paused    bool
+	pause     *item
+	handsFree bool
+	hands     *item
+	profiles  choices
+	sources   choices
+	history   choices
+}
+
+// addMenu builds the menu, it's called by Run once the tray is ready.
+func addMenu() {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.pause = addItem("Pause dictation", "Ignore hotkeys until resumed", true)
+	watch(menu.pause, func() (Action, bool) {
+		if menu.paused {
+			return Action{Kind: Resume}, true
+		}
+		return Action{Kind: Pause}, true
+	})
+	menu.hands = addItem("Hands-free", "Listen continuously and type each sentence", true)
+	watch(menu.hands, send(HandsFree))
+	addSeparator()
+	menu.profiles.kind = SelectProfile
+	addChoices(&menu.profiles, addItem("Profile", "Profile used by the default hotkey", false))
+	menu.sources.kind = SelectSource
+	addChoices(&menu.sources, addItem("Microphone", "Audio source to record from", false))
+	addSeparator()
+	repeat := addItem("Repeat last", "Emit the last transcript again", false)
+	watch(repeat, send(RepeatLast))
+	copyLast := addItem("Copy last", "Copy the last transcript to the clipboard", false)
+	watch(copyLast, send(CopyLast))
+	menu.history.kind = Copy
+	addChoices(&menu.history, addItem("History", "Copy a recent transcript to the clipboard", false))
+	addSeparator()
+	config := addItem("Open configuration", "Open the configuration file", false)
+	watch(config, send(OpenConfig))
+	quit := addItem("Quit", "Stop whisperd", false)
+	watch(quit, send(Quit))
+	menu.ready = true
+	updateMenu()
+}
+
+// addChoices creates the entries of the submenu c under parent.
+func addChoices(c *choices, parent *item) {
+	c.parent = parent
+	for i := range maxChoices {
+		it := parent.addSub("", "")
+		it.Hide()
+		c.items = append(c.items, it)
+		watch(it, func() (Action, bool) {
+			if i >= len(c.values) {
+				return Action{}, false
+			}
+			return Action{Kind: c.kind, Value: c.values[i].Value}, true
+		})
+	}
+}
+
+// watch sends the action returned by fn every time the item is clicked.
+// fn is called with the menu locked.
+func watch(it *item, fn func() (Action, bool)) {
+	go func() {
+		for range it.clicked {
+			menu.Lock()
+			a, ok := fn()
+			menu.Unlock()
+			if ok {
+				actions <- a
+			}
+		}
+	}()
+}
+
+// send returns a watch function for a fixed action.
+func send(kind ActionKind) func() (Action, bool) {
+	return func() (Action, bool) {
+		return Action{Kind: kind}, true
+	}
+}
+
+// updateMenu refreshes the menu items, the menu must be locked.
+func updateMenu() {
+	if !menu.ready {
+		return
+	}
+	if menu.paused {
+		menu.pause.Check()
+	} else {
+		menu.pause.Uncheck()
+	}
+	if menu.handsFree {
+		menu.hands.Check()
+	} else {
+		menu.hands.Uncheck()
+	}
+	menu.profiles.update()
+	menu.sources.update()
+	menu.history.update()
+}
+
+func (c *choices) update() {
+	if len(c.values) == 0 {
+		c.parent.Disable()
+	} else {
+		c.parent.Enable()
+	}
+	selected := slices.IndexFunc(c.values, func(v Choice) bool {
+		return v.Value == c.selected
+	})
+	if selected < 0 && c.kind != Copy {
+		selected = 0
+	}
+	for i, it := range c.items {
+		if i >= len(c.values) {
+			it.Hide()
+			continue
+		}
+		it.SetTitle(c.values[i].Label)
+		if i == selected && c.kind != Copy {
+			it.Check()
+		} else {
+			it.Uncheck()
+		}
+		it.Show()
+	}
+}
+
+// SetPaused updates the pause menu item.
+func SetPaused(paused bool) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.paused = paused
+	updateMenu()
+}
+
+// SetHandsFree updates the hands-free menu item.
+func SetHandsFree(running bool) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.handsFree = running
+	updateMenu()
+}
+
+// SetProfiles sets the profiles that can be selected.
+func SetProfiles(profiles []Choice) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.profiles.values = profiles
+	updateMenu()
+}
+
+// SetSources sets the audio sources that can be selected.
+func SetSources(sources []Choice) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.sources.values = sources
+	updateMenu()
+}
+
+// SetSelected checks the active profile and source. The first entry
+// is checked if a value doesn't match any entry.
+func SetSelected(profile, source string) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.profiles.selected = profile
+	menu.sources.selected = source
+	updateMenu()
+}
+
+// SetHistory sets the recent transcripts, newest first.
+func SetHistory(texts []string) {
+	menu.Lock()
+	defer menu.Unlock()
+	menu.history.values = nil
+	for _, text := range texts {
+		menu.history.values = append(menu.history.values, Choice{
+			Value: text,
+			Label: truncate(text, 40),
+		})
+	}
+	updateMenu()
+}
+
+// truncate shortens text to a single line of at most n runes.
+func truncate(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return text
+}
@@ -14,7 +14,8 @@ func Run(ready func()) {
 		select {}
 	}
 	systray.Run(func() {
-		SetStatus(Idle)
+		SetStatus(Idle, "")
+		addMenu()
 		if ready != nil {
 			ready()
 		}
@@ -22,10 +23,52 @@ func Run(ready func()) {
 }
 
 // SetStatus updates the tray icon and tooltip.
-func SetStatus(s Status) {
+// The detail, such as an error message, is appended to the tooltip.
+func SetStatus(s Status, detail string) {
 	if !Enabled {
 		return
 	}
+	setMeterStatus(s)
 	systray.SetIcon(icons[s])
-	systray.SetTooltip(tooltips[s])
+	systray.SetTooltip(tooltip(s, detail))
+}
+
+// SetLevel animates the icon with the input level while recording.
+// The level is between 0 and 1.
+func SetLevel(level float64) {
+	if !Enabled {
+		return
+	}
+	if icon, ok := levelIcon(level); ok {
+		systray.SetIcon(icon)
+	}
+}
+
+// Stop stops the systray, which makes Run return.
+func Stop() {
+	if !Enabled {
+		return
+	}
+	systray.Quit()
+}
+
+func addItem(title, tooltip string, checkbox bool) *item {
+	if checkbox {
+		return wrap(systray.AddMenuItemCheckbox(title, tooltip, false))
+	}
+	return wrap(systray.AddMenuItem(title, tooltip))
+}
+
+func addSeparator() {
+	systray.AddSeparator()
+}
+
+func wrap(mi *systray.MenuItem) *item {
+	return &item{
+		menuItem: mi,
+		clicked:  mi.ClickedCh,
+		addSub: func(title, tooltip string) *item {
+			return wrap(mi.AddSubMenuItemCheckbox(title, tooltip, false))
+		},
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"math"
+	"sync"
 )
 
 // Status represents the current state of whisperd.
@@ -14,8 +16,47 @@ const (
 	Idle Status = iota
 	Recording
 	Transcribing
+	// Typing is shown while the transcript is emitted.
+	Typing
+	// Paused is shown while hotkeys are ignored.
+	Paused
+	// Error is shown after a dictation failed.
+	Error
+	// Offline is shown when the input devices or the API can't be reached.
+	Offline
+	// Silent is shown while recording if the microphone hasn't picked up
+	// any sound for a while.
+	Silent
+	// Listening is shown during hands-free dictation while waiting for speech.
+	Listening
 )
 
+// String returns the lowercase name of the status.
+func (s Status) String() string {
+	switch s {
+	case Idle:
+		return "idle"
+	case Recording:
+		return "recording"
+	case Transcribing:
+		return "transcribing"
+	case Typing:
+		return "typing"
+	case Paused:
+		return "paused"
+	case Error:
+		return "error"
+	case Offline:
+		return "offline"
+	case Silent:
+		return "silent"
+	case Listening:
+		return "listening"
+	default:
+		return "unknown"
+	}
+}
+
 var icons map[Status][]byte
 
 func init() {
@@ -23,10 +64,90 @@ func init() {
 		Idle:         circleIcon(color.RGBA{128, 128, 128, 255}),
 		Recording:    circleIcon(color.RGBA{220, 40, 40, 255}),
 		Transcribing: circleIcon(color.RGBA{220, 200, 40, 255}),
+		Typing:       circleIcon(color.RGBA{40, 180, 80, 255}),
+		Paused:       circleIcon(color.RGBA{60, 120, 220, 255}),
+		Error:        circleIcon(color.RGBA{240, 130, 20, 255}),
+		Offline:      ringIcon(color.RGBA{128, 128, 128, 255}, 6),
+		Silent:       ringIcon(color.RGBA{220, 40, 40, 255}, 6),
+		Listening:    circleIcon(color.RGBA{150, 60, 200, 255}),
 	}
+	for i := range levelIcons[Recording] {
+		level := float64(i) / float64(len(levelIcons[Recording])-1)
+		levelIcons[Recording][i] = pulseIcon(color.RGBA{220, 40, 40, 255}, level)
+		levelIcons[Listening][i] = pulseIcon(color.RGBA{150, 60, 200, 255}, level)
+	}
+}
+
+// levelIcons are shown while recording or listening, from silent to loud.
+var levelIcons = map[Status]*[8][]byte{
+	Recording: new([8][]byte),
+	Listening: new([8][]byte),
+}
+
+// pulseIcon draws a faint circle with a solid circle inside it whose size
+// grows with the level, which is between 0 and 1.
+func pulseIcon(c color.RGBA, level float64) []byte {
+	const size = 22
+	const center = size / 2
+	const radius = 9
+	inner := 4 + int(level*(radius-4))
+	faint := color.RGBA{c.R, c.G, c.B, 90}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			dx := x - center
+			dy := y - center
+			switch d := dx*dx + dy*dy; {
+			case d <= inner*inner:
+				img.Set(x, y, c)
+			case d <= radius*radius:
+				img.Set(x, y, faint)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// meter tracks the icon shown for the input level.
+var meter struct {
+	sync.Mutex
+	status Status
+	step   int
+}
+
+// setMeterStatus records the status shown in the tray, which resets the meter.
+func setMeterStatus(s Status) {
+	meter.Lock()
+	defer meter.Unlock()
+	meter.status = s
+	meter.step = -1
+}
+
+// levelIcon returns the icon for the level if the tray is recording or
+// listening and the icon changed since the last call.
+func levelIcon(level float64) ([]byte, bool) {
+	meter.Lock()
+	defer meter.Unlock()
+	icons, ok := levelIcons[meter.status]
+	if !ok {
+		return nil, false
+	}
+	step := int(math.Round(min(max(level, 0), 1) * float64(len(icons)-1)))
+	if step == meter.step {
+		return nil, false
+	}
+	meter.step = step
+	return icons[step], true
 }
 
 func circleIcon(c color.Color) []byte {
+	return ringIcon(c, 0)
+}
+
+// ringIcon draws a circle with a hole of the given radius.
+func ringIcon(c color.Color, hole int) []byte {
 	const size = 22
 	const center = size / 2
 	const radius = 9
@@ -35,7 +156,7 @@ func circleIcon(c color.Color) []byte {
 		for x := range size {
 			dx := x - center
 			dy := y - center
-			if dx*dx+dy*dy <= radius*radius {
+			if d := dx*dx + dy*dy; d <= radius*radius && d >= hole*hole {
 				img.Set(x, y, c)
 			}
 		}
@@ -50,8 +171,62 @@ func circleIcon(c color.Color) []byte {
 // and SetStatus is a no-op.
 var Enabled = true
 
+// ActionKind identifies a menu action.
+type ActionKind int
+
+const (
+	// RepeatLast requests that the last transcript is emitted again.
+	RepeatLast ActionKind = iota
+	// Pause requests that dictation is paused.
+	Pause
+	// Resume requests that dictation is resumed.
+	Resume
+	// HandsFree requests that hands-free dictation is started or stopped.
+	HandsFree
+	// SelectProfile requests that the profile named by Value is made active.
+	SelectProfile
+	// SelectSource requests recording from the source named by Value.
+	SelectSource
+	// CopyLast requests that the last transcript is copied to the clipboard.
+	CopyLast
+	// Copy requests that Value is copied to the clipboard.
+	Copy
+	// OpenConfig requests that the configuration file is opened.
+	OpenConfig
+	// Quit requests that whisperd exits.
+	Quit
+)
+
+// Action is a menu action selected by the user.
+type Action struct {
+	Kind  ActionKind
+	Value string
+}
+
+var actions = make(chan Action)
+
+// Actions returns the channel on which menu actions are delivered.
+func Actions() <-chan Action {
+	return actions
+}
+
 var tooltips = map[Status]string{
 	Idle:         "whisperd: idle",
 	Recording:    "whisperd: recording",
 	Transcribing: "whisperd: transcribing",
+	Typing:       "whisperd: typing",
+	Paused:       "whisperd: paused",
+	Error:        "whisperd: error",
+	Offline:      "whisperd: offline",
+	Silent:       "whisperd: recording, but the microphone is silent",
+	Listening:    "whisperd: listening",
+}
+
+// tooltip returns the tooltip for the status with an optional detail,
+// such as the error message.
+func tooltip(s Status, detail string) string {
+	if detail == "" {
+		return tooltips[s]
+	}
+	return tooltips[s] + ": " + detail
 }
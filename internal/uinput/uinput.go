@@ -36,11 +36,35 @@ type Setup struct {
 	FFEffectsMax uint32
 }
 
+// DefaultID is the identification used for devices created by Create.
+var DefaultID = InputID{
+	Bustype: 0x03, // USB
+	Vendor:  0x1234,
+	Product: 0x5678,
+}
+
+// SetName sets the device name.
+func (s *Setup) SetName(name string) error {
+	if len(name) >= len(s.Name) {
+		return fmt.Errorf("name is too long: %q", name)
+	}
+	clear(s.Name[:])
+	copy(s.Name[:], name)
+	return nil
+}
+
 // Create creates a new uinput device with the given name and returns the file descriptor.
 func Create(name string) (*os.File, error) {
-	if len(name) >= 80 {
-		return nil, fmt.Errorf("name is too long: %q", name)
+	setup := Setup{ID: DefaultID}
+	if err := setup.SetName(name); err != nil {
+		return nil, err
 	}
+	return CreateDevice(setup)
+}
+
+// CreateDevice creates a new uinput device with the given setup and returns the file descriptor.
+// The device advertises all standard keyboard keys.
+func CreateDevice(setup Setup) (*os.File, error) {
 	f, err := os.OpenFile("/dev/uinput", os.O_WRONLY|unix.O_NONBLOCK, 0)
 	if err != nil {
 		return nil, err
@@ -56,15 +80,6 @@ func Create(name string) (*os.File, error) {
 			return nil, err
 		}
 	}
-	setup := Setup{
-		ID: InputID{
-			Bustype: 0x03, // USB
-			Vendor:  0x1234,
-			Product: 0x5678,
-		},
-	}
-	copy(setup.Name[:], []byte(name))
-	setup.Name[len(name)] = 0
 	if err := unix.IoctlSetPointerInt(fd, UI_DEV_SETUP, int(uintptr(unsafe.Pointer(&setup)))); err != nil {
 		f.Close()
 		return nil, err
@@ -108,28 +123,42 @@ func EmitText(f *os.File, text string) error {
 		if !ok {
 			continue
 		}
-		batch := []inputcodes.Event{}
-		// key down
-		for _, e := range ee {
-			e.Value = 1
-			batch = append(batch, e)
-		}
-		batch = append(batch, inputcodes.Event{
-			Type: inputcodes.EV_SYN,
-			Code: inputcodes.SYN_REPORT,
-		})
-		// key up
-		for _, e := range ee {
-			e.Value = 0
-			batch = append(batch, e)
-		}
-		batch = append(batch, inputcodes.Event{
-			Type: inputcodes.EV_SYN,
-			Code: inputcodes.SYN_REPORT,
-		})
-		if err := Emit(f, batch); err != nil {
+		if err := emitPress(f, ee); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// EmitChord presses and releases the given keys together, in order.
+func EmitChord(f *os.File, keys []uint16) error {
+	ee := make([]inputcodes.Event, len(keys))
+	for i, key := range keys {
+		ee[i] = inputcodes.Event{Type: inputcodes.EV_KEY, Code: key}
+	}
+	return emitPress(f, ee)
+}
+
+// emitPress emits key down events for ee followed by key up events.
+func emitPress(f *os.File, ee []inputcodes.Event) error {
+	batch := []inputcodes.Event{}
+	// key down
+	for _, e := range ee {
+		e.Value = 1
+		batch = append(batch, e)
+	}
+	batch = append(batch, inputcodes.Event{
+		Type: inputcodes.EV_SYN,
+		Code: inputcodes.SYN_REPORT,
+	})
+	// key up
+	for _, e := range ee {
+		e.Value = 0
+		batch = append(batch, e)
+	}
+	batch = append(batch, inputcodes.Event{
+		Type: inputcodes.EV_SYN,
+		Code: inputcodes.SYN_REPORT,
+	})
+	return Emit(f, batch)
+}
@@ -0,0 +1,127 @@
+package vad
+
+import (
+	"cmp"
+	"time"
+)
+
+// DefaultEndSilence is the zero value of Segmenter.EndSilence.
+const DefaultEndSilence = 800 * time.Millisecond
+
+// Segmenter splits a stream of PCM into utterances. Unlike Detector.Detect,
+// which sees the whole recording, it tracks the noise floor as it goes.
+type Segmenter struct {
+	Detector Detector
+	// EndSilence is the pause which ends an utterance.
+	EndSilence time.Duration
+	// MaxLength ends utterances which grow longer, zero for no limit.
+	MaxLength time.Duration
+
+	pending  []byte // partial frame
+	floor    float64
+	frames   int // frames seen, used to settle the noise floor
+	padding  [][]byte
+	speech   []byte // utterance in progress, nil outside of speech
+	voiced   int    // speech frames in the utterance
+	run      int    // consecutive speech frames
+	silent   int    // consecutive silent frames
+	lastTalk int    // frame index of the last speech
+}
+
+// Write feeds PCM to the segmenter and returns the utterances it completed.
+func (s *Segmenter) Write(p []byte) [][]byte {
+	size := s.Detector.FrameSize()
+	data := append(s.pending, p...)
+	var done [][]byte
+	for len(data) >= size {
+		if u := s.frame(data[:size]); u != nil {
+			done = append(done, u)
+		}
+		data = data[size:]
+	}
+	s.pending = append([]byte(nil), data...)
+	return done
+}
+
+// Flush returns the utterance in progress, if it has enough speech.
+func (s *Segmenter) Flush() []byte {
+	u := s.end()
+	s.pending = nil
+	return u
+}
+
+// Silence returns how long it has been since the last speech.
+func (s *Segmenter) Silence() time.Duration {
+	return time.Duration(s.frames-s.lastTalk) * frameDuration
+}
+
+// frame processes a single frame and returns a completed utterance.
+func (s *Segmenter) frame(f []byte) []byte {
+	a := Analyze(f)
+	if s.frames == 0 {
+		s.floor = min(a.DB, maxFloorDB)
+	}
+	s.frames++
+	speech := s.Detector.IsSpeech(a, s.floor)
+	if !speech {
+		// fall quickly and rise slowly, so that speech doesn't raise the floor
+		if a.DB < s.floor {
+			s.floor = a.DB
+		} else {
+			s.floor += (a.DB - s.floor) * 0.02
+		}
+		s.floor = min(max(s.floor, floorDB), maxFloorDB)
+	}
+	padFrames := int(cmp.Or(s.Detector.Padding, DefaultPadding) / frameDuration)
+	if s.speech == nil {
+		s.padding = append(s.padding, f)
+		if speech {
+			s.run++
+		} else {
+			s.run = 0
+		}
+		if s.run >= minRun {
+			// start the utterance with the padding before the speech
+			start := max(0, len(s.padding)-s.run-padFrames)
+			for _, p := range s.padding[start:] {
+				s.speech = append(s.speech, p...)
+			}
+			s.voiced = s.run
+			s.silent = 0
+			s.lastTalk = s.frames
+			s.padding = nil
+		} else if len(s.padding) > padFrames+minRun {
+			s.padding = s.padding[1:]
+		}
+		return nil
+	}
+	s.speech = append(s.speech, f...)
+	if s.MaxLength > 0 && s.Detector.Duration(len(s.speech)) >= s.MaxLength {
+		return s.end()
+	}
+	if speech {
+		s.voiced++
+		s.silent = 0
+		s.lastTalk = s.frames
+		return nil
+	}
+	s.silent++
+	if time.Duration(s.silent)*frameDuration < cmp.Or(s.EndSilence, DefaultEndSilence) {
+		return nil
+	}
+	// drop the trailing silence beyond the padding
+	if extra := s.silent - padFrames; extra > 0 {
+		s.speech = s.speech[:len(s.speech)-extra*len(f)]
+	}
+	return s.end()
+}
+
+// end finishes the utterance in progress.
+func (s *Segmenter) end() []byte {
+	u, voiced := s.speech, s.voiced
+	s.speech, s.voiced, s.run, s.silent = nil, 0, 0, 0
+	if time.Duration(voiced)*frameDuration < cmp.Or(s.Detector.MinSpeech, DefaultMinSpeech) {
+		return nil
+	}
+	return u
+}
@@ -0,0 +1,47 @@
+package vad
+
+import "time"
+
+// splitWindow is the length of the pause looked for when splitting.
+const splitWindow = 200 * time.Millisecond
+
+// Split divides the PCM into chunks no longer than limit. Each chunk ends in
+// the quietest part of its second half, so that words aren't cut in two.
+// The chunks are slices of pcm.
+func (d *Detector) Split(pcm []byte, limit time.Duration) [][]byte {
+	size := d.FrameSize()
+	n := int(limit/frameDuration) * size
+	if limit <= 0 || len(pcm) <= n || n < 2*size {
+		return [][]byte{pcm}
+	}
+	window := int(splitWindow / frameDuration)
+	var chunks [][]byte
+	for len(pcm) > n {
+		cut := quietest(pcm[n/2:n], size, window) + n/2
+		chunks = append(chunks, pcm[:cut])
+		pcm = pcm[cut:]
+	}
+	return append(chunks, pcm)
+}
+
+// quietest returns the byte offset of the middle of the quietest run of
+// window frames in the PCM.
+func quietest(pcm []byte, size, window int) int {
+	var levels []float64
+	for i := 0; i+size <= len(pcm); i += size {
+		levels = append(levels, Analyze(pcm[i:i+size]).DB)
+	}
+	window = min(window, len(levels))
+	var sum float64
+	for _, l := range levels[:window] {
+		sum += l
+	}
+	best, bestSum := 0, sum
+	for i := window; i < len(levels); i++ {
+		sum += levels[i] - levels[i-window]
+		if sum < bestSum {
+			best, bestSum = i-window+1, sum
+		}
+	}
+	return (best + window/2) * size
+}
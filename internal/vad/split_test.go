@@ -0,0 +1,79 @@
+package vad
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		pcm   []byte
+		limit time.Duration
+		// cuts are the ranges the chunks must end in, except the last
+		cuts [][2]time.Duration
+	}{
+		{
+			name:  "shorter than the limit",
+			pcm:   record(part{d: 3 * time.Second, speech: true}),
+			limit: 5 * time.Second,
+		},
+		{
+			name:  "no limit",
+			pcm:   record(part{d: 3 * time.Second, speech: true}),
+			limit: 0,
+		},
+		{
+			name: "cut in the pause",
+			pcm: record(
+				part{d: 3500 * time.Millisecond, speech: true},
+				part{d: 400 * time.Millisecond},
+				part{d: 4 * time.Second, speech: true},
+			),
+			limit: 5 * time.Second,
+			cuts:  [][2]time.Duration{{3500 * time.Millisecond, 3900 * time.Millisecond}},
+		},
+		{
+			name: "pauses in the first half are ignored",
+			pcm: record(
+				part{d: time.Second, speech: true},
+				part{d: 400 * time.Millisecond},
+				part{d: 2 * time.Second, speech: true},
+				part{d: 400 * time.Millisecond},
+				part{d: 2 * time.Second, speech: true},
+			),
+			limit: 5 * time.Second,
+			cuts:  [][2]time.Duration{{3400 * time.Millisecond, 3800 * time.Millisecond}},
+		},
+		{
+			name:  "hard cut without a pause",
+			pcm:   record(part{d: 6 * time.Second, speech: true}),
+			limit: 5 * time.Second,
+			cuts:  [][2]time.Duration{{2500 * time.Millisecond, 5 * time.Second}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Detector
+			chunks := d.Split(tt.pcm, tt.limit)
+			if len(chunks) != len(tt.cuts)+1 {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.cuts)+1)
+			}
+			if joined := bytes.Join(chunks, nil); !bytes.Equal(joined, tt.pcm) {
+				t.Error("the chunks don't add up to the recording")
+			}
+			for i, chunk := range chunks {
+				length := d.Duration(len(chunk))
+				if tt.limit > 0 && length > tt.limit {
+					t.Errorf("chunk %d is %v, longer than %v", i, length, tt.limit)
+				}
+				if i < len(tt.cuts) {
+					if cut := tt.cuts[i]; length < cut[0] || length > cut[1] {
+						t.Errorf("chunk %d ends after %v, want between %v and %v", i, length, cut[0], cut[1])
+					}
+				}
+			}
+		})
+	}
+}
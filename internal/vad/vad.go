@@ -0,0 +1,176 @@
+package vad
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math"
+	"slices"
+	"time"
+)
+
+// Detector finds speech in signed 16-bit little endian mono PCM using the
+// energy and zero crossing rate of short frames. A frame is speech if it's
+// Threshold dB louder than the noise floor, or a little quieter but with the
+// high zero crossing rate of consonants like "s" and "f".
+type Detector struct {
+	SampleRate int
+	// Threshold is how far above the noise floor speech is, in dB.
+	Threshold float64
+	// MinSpeech is the least amount of speech a recording needs.
+	MinSpeech time.Duration
+	// Padding is the audio kept around speech when trimming, it also
+	// joins speech separated by shorter pauses.
+	Padding time.Duration
+}
+
+// Defaults for the zero values of the Detector fields.
+const (
+	DefaultSampleRate = 16000
+	DefaultThreshold  = 12
+	DefaultMinSpeech  = 250 * time.Millisecond
+	DefaultPadding    = 200 * time.Millisecond
+)
+
+const (
+	frameDuration = 20 * time.Millisecond
+	// floorDB is the quietest noise floor, digital silence is clamped to it
+	floorDB = -70
+	// maxFloorDB is the loudest noise floor, so that recordings without
+	// pauses aren't mistaken for noise
+	maxFloorDB = -45
+	// minSpeechDB is the quietest audio which can be speech
+	minSpeechDB = -55
+	// fricativeZCR is the zero crossing rate of unvoiced consonants
+	fricativeZCR = 0.3
+	// frames shorter than this are noise
+	minRun = 3
+)
+
+// Segment is a range of speech, as byte offsets into the PCM.
+type Segment struct {
+	Start int
+	End   int
+}
+
+// Frame is the analysis of a single frame.
+type Frame struct {
+	DB  float64 // RMS level in dBFS
+	ZCR float64 // zero crossings per sample
+}
+
+// Analyze returns the level and zero crossing rate of a frame.
+func Analyze(frame []byte) Frame {
+	n := len(frame) / 2
+	if n == 0 {
+		return Frame{DB: floorDB}
+	}
+	var sumSq float64
+	var crossings int
+	var prev int16
+	for i := range n {
+		v := int16(binary.LittleEndian.Uint16(frame[2*i:]))
+		f := float64(v) / math.MaxInt16
+		sumSq += f * f
+		if i > 0 && (v < 0) != (prev < 0) {
+			crossings++
+		}
+		prev = v
+	}
+	db := 10 * math.Log10(sumSq/float64(n))
+	return Frame{
+		DB:  max(db, floorDB),
+		ZCR: float64(crossings) / float64(n),
+	}
+}
+
+// IsSpeech reports whether the frame is speech given the noise floor in dBFS.
+func (d *Detector) IsSpeech(f Frame, floor float64) bool {
+	threshold := cmp.Or(d.Threshold, DefaultThreshold)
+	if f.DB < minSpeechDB {
+		return false
+	}
+	if f.DB >= floor+threshold {
+		return true
+	}
+	return f.DB >= floor+threshold/2 && f.ZCR >= fricativeZCR
+}
+
+// FrameSize returns the number of bytes in a frame.
+func (d *Detector) FrameSize() int {
+	rate := cmp.Or(d.SampleRate, DefaultSampleRate)
+	return rate * int(frameDuration/time.Millisecond) / 1000 * 2
+}
+
+// Duration returns the duration of n bytes of PCM.
+func (d *Detector) Duration(n int) time.Duration {
+	rate := cmp.Or(d.SampleRate, DefaultSampleRate)
+	return time.Duration(n/2) * time.Second / time.Duration(rate)
+}
+
+// Detect returns the speech in the PCM. The noise floor is estimated from
+// the quietest frames of the recording.
+func (d *Detector) Detect(pcm []byte) []Segment {
+	segments, _ := d.detect(pcm)
+	return segments
+}
+
+// detect returns the speech segments and the number of speech frames.
+func (d *Detector) detect(pcm []byte) ([]Segment, int) {
+	size := d.FrameSize()
+	var frames []Frame
+	for i := 0; i+size <= len(pcm); i += size {
+		frames = append(frames, Analyze(pcm[i:i+size]))
+	}
+	if len(frames) == 0 {
+		return nil, 0
+	}
+	floor := noiseFloor(frames)
+	padding := int(cmp.Or(d.Padding, DefaultPadding) / frameDuration)
+	var segments []Segment
+	var speech int
+	for i := 0; i < len(frames); {
+		if !d.IsSpeech(frames[i], floor) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(frames) && d.IsSpeech(frames[j], floor) {
+			j++
+		}
+		if j-i >= minRun {
+			speech += j - i
+			start := max(0, i-padding) * size
+			end := min(len(frames), j+padding) * size
+			if n := len(segments); n > 0 && start <= segments[n-1].End {
+				segments[n-1].End = end
+			} else {
+				segments = append(segments, Segment{Start: start, End: end})
+			}
+		}
+		i = j
+	}
+	return segments, speech
+}
+
+// Trim removes the silence before and after the speech, keeping the
+// padding, and returns the remaining PCM with the duration of speech it
+// contains. Pauses between words are left alone, since Whisper uses them
+// for punctuation. It returns nil if there's less than MinSpeech.
+func (d *Detector) Trim(pcm []byte) ([]byte, time.Duration) {
+	segments, frames := d.detect(pcm)
+	speech := time.Duration(frames) * frameDuration
+	if len(segments) == 0 || speech < cmp.Or(d.MinSpeech, DefaultMinSpeech) {
+		return nil, speech
+	}
+	return pcm[segments[0].Start:segments[len(segments)-1].End], speech
+}
+
+// noiseFloor estimates the noise floor as the level of the 10th percentile frame.
+func noiseFloor(frames []Frame) float64 {
+	levels := make([]float64, len(frames))
+	for i, f := range frames {
+		levels[i] = f.DB
+	}
+	slices.Sort(levels)
+	return min(levels[len(levels)/10], maxFloorDB)
+}
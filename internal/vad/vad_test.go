@@ -0,0 +1,159 @@
+package vad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// part is a stretch of a synthetic recording.
+type part struct {
+	d      time.Duration
+	speech bool
+}
+
+// record returns 16kHz PCM with a 300Hz tone for the speech parts and
+// quiet noise for the others.
+func record(parts ...part) []byte {
+	rng := rand.New(rand.NewSource(1))
+	var pcm []byte
+	var n int
+	for _, p := range parts {
+		for range int(p.d.Seconds() * DefaultSampleRate) {
+			v := 30 * rng.NormFloat64()
+			if p.speech {
+				v += 10000 * math.Sin(2*math.Pi*300*float64(n)/DefaultSampleRate)
+			}
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(v)))
+			n++
+		}
+	}
+	return pcm
+}
+
+// offset returns the byte offset of a time in a 16kHz recording.
+func offset(d time.Duration) int {
+	return int(d.Seconds()*DefaultSampleRate) * 2
+}
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector Detector
+		pcm      []byte
+		start    time.Duration // of the trimmed PCM in the recording
+		end      time.Duration
+		speech   time.Duration
+		skipped  bool
+	}{
+		{
+			name:    "silence",
+			pcm:     record(part{d: 2 * time.Second}),
+			skipped: true,
+		},
+		{
+			name:    "empty",
+			skipped: true,
+		},
+		{
+			name: "edges keep the padding",
+			pcm: record(
+				part{d: time.Second},
+				part{d: 500 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			start:  800 * time.Millisecond,
+			end:    1700 * time.Millisecond,
+			speech: 500 * time.Millisecond,
+		},
+		{
+			name: "pauses between words are kept",
+			pcm: record(
+				part{d: time.Second},
+				part{d: 500 * time.Millisecond, speech: true},
+				part{d: time.Second},
+				part{d: 500 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			start:  800 * time.Millisecond,
+			end:    3200 * time.Millisecond,
+			speech: time.Second,
+		},
+		{
+			name: "speech at the edges of the recording",
+			pcm: record(
+				part{d: 500 * time.Millisecond, speech: true},
+				part{d: 500 * time.Millisecond},
+				part{d: 500 * time.Millisecond, speech: true},
+			),
+			start:  0,
+			end:    1500 * time.Millisecond,
+			speech: time.Second,
+		},
+		{
+			name:     "custom padding",
+			detector: Detector{Padding: 100 * time.Millisecond},
+			pcm: record(
+				part{d: time.Second},
+				part{d: 500 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			start:  900 * time.Millisecond,
+			end:    1600 * time.Millisecond,
+			speech: 500 * time.Millisecond,
+		},
+		{
+			name: "less than the minimum speech",
+			pcm: record(
+				part{d: time.Second},
+				part{d: 200 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			speech:  200 * time.Millisecond,
+			skipped: true,
+		},
+		{
+			name:     "lower minimum speech",
+			detector: Detector{MinSpeech: 100 * time.Millisecond},
+			pcm: record(
+				part{d: time.Second},
+				part{d: 200 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			start:  800 * time.Millisecond,
+			end:    1400 * time.Millisecond,
+			speech: 200 * time.Millisecond,
+		},
+		{
+			name:     "clicks aren't speech",
+			detector: Detector{MinSpeech: 20 * time.Millisecond},
+			pcm: record(
+				part{d: time.Second},
+				part{d: 40 * time.Millisecond, speech: true},
+				part{d: time.Second},
+			),
+			skipped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trimmed, speech := tt.detector.Trim(tt.pcm)
+			if speech != tt.speech {
+				t.Errorf("got %v of speech, want %v", speech, tt.speech)
+			}
+			if tt.skipped {
+				if trimmed != nil {
+					t.Errorf("got %v of audio, want nil", tt.detector.Duration(len(trimmed)))
+				}
+				return
+			}
+			want := tt.pcm[offset(tt.start):offset(tt.end)]
+			if !bytes.Equal(trimmed, want) {
+				t.Errorf("got %v of audio, want %v to %v", tt.detector.Duration(len(trimmed)), tt.start, tt.end)
+			}
+		})
+	}
+}
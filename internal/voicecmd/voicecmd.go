@@ -0,0 +1,213 @@
+package voicecmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/icholy/whisperd/internal/inputcodes"
+)
+
+// Chord is a set of keys pressed together, in order.
+type Chord []uint16
+
+// Command maps a spoken phrase to a sequence of key chords.
+type Command struct {
+	Phrase string
+	Chords []Chord
+}
+
+// Table is a list of voice commands.
+type Table []Command
+
+// DefaultTable is used when no command table file is provided. Keys whose
+// names are ordinary words, like "undo", need the "press" prefix so that
+// prose which mentions them isn't turned into key presses.
+var DefaultTable = Table{
+	{Phrase: "new line", Chords: []Chord{{inputcodes.KEY_ENTER}}},
+	{Phrase: "new paragraph", Chords: []Chord{{inputcodes.KEY_ENTER}, {inputcodes.KEY_ENTER}}},
+	{Phrase: "press enter", Chords: []Chord{{inputcodes.KEY_ENTER}}},
+	{Phrase: "press tab", Chords: []Chord{{inputcodes.KEY_TAB}}},
+	{Phrase: "press escape", Chords: []Chord{{inputcodes.KEY_ESC}}},
+	{Phrase: "press backspace", Chords: []Chord{{inputcodes.KEY_BACKSPACE}}},
+	{Phrase: "delete word", Chords: []Chord{{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_BACKSPACE}}},
+	{Phrase: "delete line", Chords: []Chord{{inputcodes.KEY_LEFTSHIFT, inputcodes.KEY_HOME}, {inputcodes.KEY_BACKSPACE}}},
+	{Phrase: "select all", Chords: []Chord{{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_A}}},
+	{Phrase: "press undo", Chords: []Chord{{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_Z}}},
+	{Phrase: "go left", Chords: []Chord{{inputcodes.KEY_LEFT}}},
+	{Phrase: "go right", Chords: []Chord{{inputcodes.KEY_RIGHT}}},
+	{Phrase: "go up", Chords: []Chord{{inputcodes.KEY_UP}}},
+	{Phrase: "go down", Chords: []Chord{{inputcodes.KEY_DOWN}}},
+	{Phrase: "go home", Chords: []Chord{{inputcodes.KEY_HOME}}},
+	{Phrase: "go end", Chords: []Chord{{inputcodes.KEY_END}}},
+}
+
+// Load reads a command table from the file at path.
+func Load(path string) (Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse reads a command table. Each line has the form:
+//
+//	phrase = CHORD [CHORD...]
+//
+// where a chord is a list of key names joined by '+', e.g. "select all = LEFTCTRL+A".
+// Key names are the KEY_ constants without the prefix. Empty lines and lines
+// starting with '#' are ignored.
+func Parse(r io.Reader) (Table, error) {
+	var t Table
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		phrase, keys, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", n)
+		}
+		cmd := Command{Phrase: strings.Join(words(phrase), " ")}
+		if cmd.Phrase == "" {
+			return nil, fmt.Errorf("line %d: empty phrase", n)
+		}
+		for _, field := range strings.Fields(keys) {
+			var chord Chord
+			for _, name := range strings.Split(field, "+") {
+				name = strings.TrimPrefix(strings.ToUpper(name), "KEY_")
+				code, ok := inputcodes.KeyNames[name]
+				if !ok {
+					return nil, fmt.Errorf("line %d: unknown key %q", n, name)
+				}
+				chord = append(chord, code)
+			}
+			cmd.Chords = append(cmd.Chords, chord)
+		}
+		if len(cmd.Chords) == 0 {
+			return nil, fmt.Errorf("line %d: no keys", n)
+		}
+		t = append(t, cmd)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Segment is a piece of transcribed output. It contains either text to type
+// or a sequence of key chords to press.
+type Segment struct {
+	Text   string
+	Chords []Chord
+}
+
+// trailingPunct is the punctuation dropped after a command.
+const trailingPunct = ".,;:!?…"
+
+// Apply splits the text into segments, replacing spoken command phrases with
+// their key chords. Matching is case insensitive, ignores punctuation, and
+// prefers the longest phrase. Whitespace around a command and sentence
+// punctuation after it are dropped.
+func (t Table) Apply(text string) []Segment {
+	toks := tokenize(text)
+	var segments []Segment
+	start := 0 // byte offset of pending text
+	for i := 0; i < len(toks); {
+		cmd, n := t.match(toks[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		if s := strings.TrimRightFunc(text[start:toks[i].start], unicode.IsSpace); s != "" {
+			segments = append(segments, Segment{Text: s})
+		}
+		segments = append(segments, Segment{Chords: cmd.Chords})
+		i += n
+		start = toks[i-1].end
+		// skip the punctuation Whisper puts after the phrase, but not
+		// opening punctuation like brackets and quotes
+		for start < len(text) {
+			r, size := utf8.DecodeRuneInString(text[start:])
+			if !strings.ContainsRune(trailingPunct, r) && !unicode.IsSpace(r) {
+				break
+			}
+			start += size
+		}
+	}
+	if start < len(text) {
+		segments = append(segments, Segment{Text: text[start:]})
+	}
+	return segments
+}
+
+func (t Table) match(toks []token) (Command, int) {
+	var best Command
+	var bestN int
+	for _, cmd := range t {
+		phrase := strings.Fields(cmd.Phrase)
+		if len(phrase) <= bestN || len(phrase) > len(toks) {
+			continue
+		}
+		ok := true
+		for i, w := range phrase {
+			if toks[i].word != w {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			best, bestN = cmd, len(phrase)
+		}
+	}
+	return best, bestN
+}
+
+// token is a normalized word and its byte offsets in the original text.
+type token struct {
+	word       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var toks []token
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				if w := normalize(text[start:i]); w != "" {
+					toks = append(toks, token{word: w, start: start, end: i})
+				}
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return toks
+}
+
+func normalize(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, unicode.IsPunct))
+}
+
+func words(s string) []string {
+	var ww []string
+	for _, w := range strings.Fields(s) {
+		if w = normalize(w); w != "" {
+			ww = append(ww, w)
+		}
+	}
+	return ww
+}
@@ -0,0 +1,98 @@
+package voicecmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/icholy/whisperd/internal/inputcodes"
+)
+
+func TestParse(t *testing.T) {
+	input := `
+# comment
+New Line = ENTER
+select all = LEFTCTRL+A
+delete line = key_leftshift+HOME BACKSPACE
+`
+	got, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{
+		{Phrase: "new line", Chords: []Chord{{inputcodes.KEY_ENTER}}},
+		{Phrase: "select all", Chords: []Chord{{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_A}}},
+		{Phrase: "delete line", Chords: []Chord{{inputcodes.KEY_LEFTSHIFT, inputcodes.KEY_HOME}, {inputcodes.KEY_BACKSPACE}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"new line ENTER", "line 1: missing '='"},
+		{"= ENTER", "line 1: empty phrase"},
+		{"\nnew line =", "line 2: no keys"},
+		{"new line = NOPE", `line 1: unknown key "NOPE"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Parse(%q) = %v, want %q", tt.input, err, tt.err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	enter := Chord{inputcodes.KEY_ENTER}
+	undo := Chord{inputcodes.KEY_LEFTCTRL, inputcodes.KEY_Z}
+	tests := []struct {
+		text string
+		want []Segment
+	}{
+		{
+			text: "hello world",
+			want: []Segment{{Text: "hello world"}},
+		},
+		{
+			text: "Dear Bob, new line. How are you?",
+			want: []Segment{{Text: "Dear Bob,"}, {Chords: []Chord{enter}}, {Text: "How are you?"}},
+		},
+		{
+			text: "first New Paragraph second",
+			want: []Segment{{Text: "first"}, {Chords: []Chord{enter, enter}}, {Text: "second"}},
+		},
+		{
+			text: "I want to undo that change",
+			want: []Segment{{Text: "I want to undo that change"}},
+		},
+		{
+			text: "oops, press undo",
+			want: []Segment{{Text: "oops,"}, {Chords: []Chord{undo}}},
+		},
+		{
+			// multi-byte punctuation after a command is dropped whole, but
+			// opening quotes are kept
+			text: "new line… «quoted»",
+			want: []Segment{{Chords: []Chord{enter}}, {Text: "«quoted»"}},
+		},
+		{
+			text: "as shown, new line (see below)",
+			want: []Segment{{Text: "as shown,"}, {Chords: []Chord{enter}}, {Text: "(see below)"}},
+		},
+		{
+			text: `new line: "quoted"`,
+			want: []Segment{{Chords: []Chord{enter}}, {Text: `"quoted"`}},
+		},
+	}
+	for _, tt := range tests {
+		got := DefaultTable.Apply(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Apply(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
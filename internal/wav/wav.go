@@ -2,6 +2,8 @@ package wav
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -51,3 +53,68 @@ func Write(w io.Writer, pcm []byte, opt Options) error {
 	_, err := w.Write(pcm)
 	return err
 }
+
+// Read reads a 16-bit PCM WAV file and returns its samples and format.
+func Read(r io.Reader) ([]byte, Options, error) {
+	var riff struct {
+		ChunkID   [4]byte
+		ChunkSize uint32
+		Format    [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return nil, Options{}, err
+	}
+	if string(riff.ChunkID[:]) != "RIFF" || string(riff.Format[:]) != "WAVE" {
+		return nil, Options{}, errors.New("wav: not a WAVE file")
+	}
+	var opt Options
+	var haveFormat bool
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("wav: missing data chunk")
+			}
+			return nil, Options{}, err
+		}
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			var f struct {
+				AudioFormat   uint16
+				NumChannels   uint16
+				SampleRate    uint32
+				ByteRate      uint32
+				BlockAlign    uint16
+				BitsPerSample uint16
+			}
+			if err := binary.Read(r, binary.LittleEndian, &f); err != nil {
+				return nil, Options{}, err
+			}
+			if f.AudioFormat != 1 || f.BitsPerSample != 16 {
+				return nil, Options{}, fmt.Errorf("wav: unsupported format %d with %d bits per sample", f.AudioFormat, f.BitsPerSample)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(chunk.Size)-16); err != nil {
+				return nil, Options{}, err
+			}
+			opt = Options{NumChannels: int(f.NumChannels), SampleRate: int(f.SampleRate)}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, Options{}, errors.New("wav: data before fmt chunk")
+			}
+			pcm := make([]byte, chunk.Size)
+			if _, err := io.ReadFull(r, pcm); err != nil {
+				return nil, Options{}, err
+			}
+			return pcm, opt, nil
+		default:
+			// chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, int64(chunk.Size+chunk.Size%2)); err != nil {
+				return nil, Options{}, err
+			}
+		}
+	}
+}
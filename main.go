@@ -2,60 +2,363 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/icholy/whisperd/internal/config"
+	"github.com/icholy/whisperd/internal/control"
 	"github.com/icholy/whisperd/internal/daemon"
+	"github.com/icholy/whisperd/internal/dbusapi"
+	"github.com/icholy/whisperd/internal/dsp"
 	"github.com/icholy/whisperd/internal/inputcodes"
-	"github.com/icholy/whisperd/internal/openai"
+	"github.com/icholy/whisperd/internal/notify"
+	"github.com/icholy/whisperd/internal/postproc"
+	"github.com/icholy/whisperd/internal/secret"
 	"github.com/icholy/whisperd/internal/tray"
 	"github.com/icholy/whisperd/internal/uinput"
+	"github.com/icholy/whisperd/internal/vad"
 )
 
 func main() {
-	var inputPath, openaiKey, openaiBaseURL string
-	var keyCode int
-	var dump bool
-	flag.StringVar(&inputPath, "input", "", "device path to use. Ex: /dev/input/eventX")
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		ctl(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "list-sources" {
+		listSources(os.Args[2:])
+		return
+	}
+	var configPath, inputPath, openaiKey, openaiKeyFile, openaiBaseURL, socketPath, voiceCommandsPath, rulesPath string
+	var llmPrompt, llmModel, audioBackend, audioDevice, audioFile, audioFormat, dspNormalize string
+	var llmTimeout, errorTimeout, silenceWarning, preRoll, idleTimeout, vadMinSpeech, vadPadding, endSilence, handsFreeTimeout, maxDuration, chunkDuration time.Duration
+	var vadThreshold, dspHighPass, dspGate, dspTarget, dspMaxGain float64
+	var keyCode, repeatKeyCode, codeKeyCode int
+	var uinputName string
+	var uinputVendor, uinputProduct uint
+	var useVAD, dspRemoveDC, dump, voiceCommands, spacing, showTray, secretService, dbus, notifications, notifyTranscripts bool
+	var spacingWindow time.Duration
+	var spacingStripPeriod, chunkParallel int
+	var profiles profileFlags
+	flag.StringVar(&configPath, "config", config.DefaultPath(), "configuration file, reloaded on SIGHUP or when it changes")
+	flag.StringVar(&inputPath, "input", "", "comma separated device paths to use. Ex: /dev/input/eventX")
 	flag.IntVar(&keyCode, "key", int(inputcodes.KEY_MAIL), "Key code to use")
-	flag.StringVar(&openaiKey, "openai.key", "", "OpenAI API Key")
+	flag.IntVar(&repeatKeyCode, "repeat.key", 0, "Key code that re-emits the last transcript (0 to disable)")
+	flag.IntVar(&codeKeyCode, "code.key", 0, "Key code that dictates code instead of prose (0 to disable)")
+	flag.Var(&profiles, "profile", "additional hotkey profile (repeatable). Ex: key=184,language=fr,mode=code,output=clipboard")
+	flag.StringVar(&openaiKey, "openai.key", "", "OpenAI API Key (visible to other users, prefer -openai.key-file)")
+	flag.StringVar(&openaiKeyFile, "openai.key-file", "", "file containing the OpenAI API Key, relative to $CREDENTIALS_DIRECTORY if set")
+	flag.BoolVar(&secretService, "openai.secret-service", false, "look up the OpenAI API Key in the Secret Service (application=whisperd)")
 	flag.StringVar(&openaiBaseURL, "openai.baseurl", "", "OpenAI base url")
+	flag.StringVar(&rulesPath, "rules", "", "text post-processing rules file")
+	flag.StringVar(&llmPrompt, "llm.prompt", "", "clean up transcripts with a chat model using this system prompt or built-in prompt name (grammar, bullets, email, translate)")
+	flag.StringVar(&llmModel, "llm.model", "gpt-4o-mini", "chat model used by -llm.prompt")
+	flag.DurationVar(&llmTimeout, "llm.timeout", 5*time.Second, "maximum time to wait for the chat model before using the raw transcript")
+	flag.BoolVar(&spacing, "spacing", false, "space and capitalize dictations relative to the previous one")
+	flag.DurationVar(&spacingWindow, "spacing.window", time.Minute, "how long the previous dictation is remembered for spacing")
+	flag.IntVar(&spacingStripPeriod, "spacing.stripperiod", 0, "strip the trailing period from dictations with at most this many words")
+	flag.StringVar(&uinputName, "uinput.name", "whisperd", "name of the virtual keyboard")
+	flag.UintVar(&uinputVendor, "uinput.vendor", uint(uinput.DefaultID.Vendor), "vendor id of the virtual keyboard")
+	flag.UintVar(&uinputProduct, "uinput.product", uint(uinput.DefaultID.Product), "product id of the virtual keyboard")
+	flag.StringVar(&socketPath, "socket", control.SocketPath(), "control socket path (empty to disable)")
+	flag.BoolVar(&voiceCommands, "voicecmd", false, "replace spoken commands like \"new line\" with key presses")
+	flag.StringVar(&voiceCommandsPath, "voicecmd.file", "", "voice command table file (implies -voicecmd)")
+	flag.StringVar(&audioBackend, "audio.backend", "auto", "audio backend: auto, pipewire, pulseaudio or alsa")
+	flag.StringVar(&audioFormat, "audio.format", "wav", "file format recordings are uploaded in: wav or flac")
+	flag.StringVar(&audioDevice, "audio.device", "", "audio source to record from, see whisperd list-sources (default source if empty)")
+	flag.DurationVar(&preRoll, "audio.preroll", 0, "keep the microphone open and prepend this much audio to every recording (0 to disable)")
+	flag.DurationVar(&maxDuration, "audio.maxduration", 10*time.Minute, "stop recordings which run longer (0 for no limit)")
+	flag.DurationVar(&chunkDuration, "audio.chunk", 5*time.Minute, "split longer recordings at pauses and transcribe the chunks separately (0 to disable)")
+	flag.IntVar(&chunkParallel, "audio.chunkparallel", 1, "number of chunks transcribed at once")
+	flag.DurationVar(&idleTimeout, "audio.idletimeout", 5*time.Minute, "release the microphone after this long without dictation when using -audio.preroll (0 to never release it)")
+	flag.StringVar(&audioFile, "audio.file", "", "use this WAV file instead of recording, for testing")
+	flag.DurationVar(&silenceWarning, "audio.silencewarning", 3*time.Second, "warn in the tray when the microphone is silent for this long while recording (0 to disable)")
+	flag.BoolVar(&useVAD, "vad", false, "trim silence and skip recordings without speech")
+	flag.Float64Var(&vadThreshold, "vad.threshold", vad.DefaultThreshold, "how far above the noise floor speech is, in dB")
+	flag.DurationVar(&vadMinSpeech, "vad.minspeech", vad.DefaultMinSpeech, "recordings with less speech are skipped")
+	flag.DurationVar(&vadPadding, "vad.padding", vad.DefaultPadding, "silence kept around speech")
+	flag.BoolVar(&dspRemoveDC, "dsp.removedc", false, "remove the DC offset of recordings")
+	flag.Float64Var(&dspHighPass, "dsp.highpass", 0, "cutoff frequency of the high-pass filter in Hz (0 to disable)")
+	flag.Float64Var(&dspGate, "dsp.gate", 0, "silence audio quieter than this level in dBFS, e.g. -50 (0 to disable)")
+	flag.StringVar(&dspNormalize, "dsp.normalize", "", "normalize the peak or rms level of recordings (empty to disable)")
+	flag.Float64Var(&dspTarget, "dsp.target", 0, "normalized level in dBFS (0 for -1 with peak and -20 with rms)")
+	flag.Float64Var(&dspMaxGain, "dsp.maxgain", dsp.DefaultMaxGain, "maximum normalization gain in dB")
+	flag.DurationVar(&endSilence, "handsfree.endsilence", vad.DefaultEndSilence, "pause which ends an utterance in hands-free dictation")
+	flag.DurationVar(&handsFreeTimeout, "handsfree.timeout", 30*time.Second, "stop hands-free dictation after this long without speech (0 to keep listening)")
 	flag.BoolVar(&dump, "dump", false, "dump wav contents to files for debugging")
-	flag.BoolVar(&tray.Enabled, "tray", true, "show system tray icon")
+	flag.BoolVar(&showTray, "tray", true, "show system tray icon")
+	flag.DurationVar(&errorTimeout, "error.timeout", 10*time.Second, "how long errors are shown in the tray (0 until the next dictation)")
+	flag.BoolVar(&dbus, "dbus", true, "export the org.whisperd.Daemon service on the session bus")
+	flag.BoolVar(&notifications, "notify", false, "show desktop notifications when dictation fails")
+	flag.BoolVar(&notifyTranscripts, "notify.transcripts", false, "show a desktop notification with every transcript (implies -notify)")
 	flag.Parse()
-	if openaiKey == "" {
-		openaiKey = os.Getenv("OPENAI_API_KEY")
-	}
-	if openaiKey == "" && openaiBaseURL == "" {
-		log.Fatal("no api key found")
-	}
-	if inputPath == "" {
-		log.Fatal("missing input device path")
+	ctx := context.Background()
+	// keys keeps reloads from asking the Secret Service for the key again
+	var keys config.KeyCache
+	// load reads the configuration file and applies the command line flags on top of it
+	load := func() (*config.Config, error) {
+		cfg := config.Default()
+		if err := config.Load(configPath, cfg); err != nil {
+			// a missing file is only an error if the path was given explicitly
+			if !errors.Is(err, os.ErrNotExist) || isFlagSet("config") {
+				return nil, err
+			}
+		}
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "input":
+				cfg.Inputs = strings.Split(inputPath, ",")
+			case "repeat.key":
+				cfg.RepeatKey = config.Key(repeatKeyCode)
+			case "openai.key":
+				cfg.OpenAI.Key = secret.Secret(openaiKey)
+			case "openai.key-file":
+				cfg.OpenAI.KeyFile = openaiKeyFile
+			case "openai.secret-service":
+				cfg.OpenAI.SecretService = secretService
+			case "openai.baseurl":
+				cfg.OpenAI.BaseURL = openaiBaseURL
+			case "rules":
+				cfg.RulesFile = rulesPath
+			case "llm.model":
+				cfg.LLM.Model = llmModel
+			case "llm.timeout":
+				cfg.LLM.Timeout = llmTimeout
+			case "spacing":
+				cfg.Spacing.Enabled = spacing
+			case "spacing.window":
+				cfg.Spacing.Window = spacingWindow
+			case "spacing.stripperiod":
+				cfg.Spacing.StripPeriod = spacingStripPeriod
+			case "uinput.name":
+				cfg.Uinput.Name = uinputName
+			case "uinput.vendor":
+				cfg.Uinput.Vendor = uint16(uinputVendor)
+			case "uinput.product":
+				cfg.Uinput.Product = uint16(uinputProduct)
+			case "socket":
+				cfg.Socket = socketPath
+			case "voicecmd":
+				cfg.VoiceCommands.Enabled = voiceCommands
+			case "voicecmd.file":
+				cfg.VoiceCommands.File = voiceCommandsPath
+			case "vad":
+				cfg.VAD.Enabled = useVAD
+			case "vad.threshold":
+				cfg.VAD.Threshold = vadThreshold
+			case "vad.minspeech":
+				cfg.VAD.MinSpeech = vadMinSpeech
+			case "vad.padding":
+				cfg.VAD.Padding = vadPadding
+			case "dsp.removedc":
+				cfg.DSP.RemoveDC = dspRemoveDC
+			case "dsp.highpass":
+				cfg.DSP.HighPass = dspHighPass
+			case "dsp.gate":
+				cfg.DSP.Gate = dspGate
+			case "dsp.normalize":
+				cfg.DSP.Normalize = dspNormalize
+			case "dsp.target":
+				cfg.DSP.Target = dspTarget
+			case "dsp.maxgain":
+				cfg.DSP.MaxGain = dspMaxGain
+			case "handsfree.endsilence":
+				cfg.HandsFree.EndSilence = endSilence
+			case "handsfree.timeout":
+				cfg.HandsFree.Timeout = handsFreeTimeout
+			case "dump":
+				cfg.Audio.Dump = dump
+			case "audio.backend":
+				cfg.Audio.Backend = audioBackend
+			case "audio.format":
+				cfg.Audio.Format = audioFormat
+			case "audio.device":
+				cfg.Audio.Device = audioDevice
+			case "audio.preroll":
+				cfg.Audio.PreRoll = preRoll
+			case "audio.idletimeout":
+				cfg.Audio.IdleTimeout = idleTimeout
+			case "audio.maxduration":
+				cfg.Audio.MaxDuration = maxDuration
+			case "audio.chunk":
+				cfg.Audio.ChunkDuration = chunkDuration
+			case "audio.chunkparallel":
+				cfg.Audio.ChunkParallel = chunkParallel
+			case "audio.file":
+				cfg.Audio.File = audioFile
+			case "audio.silencewarning":
+				cfg.Audio.SilenceWarning = silenceWarning
+			case "tray":
+				cfg.Tray = showTray
+			case "dbus":
+				cfg.DBus = dbus
+			case "error.timeout":
+				cfg.ErrorTimeout = errorTimeout
+			case "notify":
+				cfg.Notify.Enabled = notifications
+			case "notify.transcripts":
+				cfg.Notify.Transcripts = notifyTranscripts
+			}
+		})
+		// the -key flag creates a default profile unless the file defines its own
+		if len(cfg.Profiles) == 0 || isFlagSet("key") {
+			cfg.Profiles = append(cfg.Profiles, config.Profile{
+				Name: "default",
+				Key:  config.Key(keyCode),
+			})
+		}
+		if codeKeyCode != 0 {
+			cfg.Profiles = append(cfg.Profiles, config.Profile{
+				Name: "code",
+				Key:  config.Key(codeKeyCode),
+				Mode: "code",
+			})
+		}
+		cfg.Profiles = append(cfg.Profiles, profiles...)
+		// -llm.prompt applies to every profile which doesn't set its own
+		if llmPrompt != "" {
+			for i := range cfg.Profiles {
+				if cfg.Profiles[i].LLM == "" {
+					cfg.Profiles[i].LLM = llmPrompt
+				}
+			}
+		}
+		if err := keys.ResolveKey(ctx, &cfg.OpenAI); err != nil {
+			return nil, err
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
 	}
-	// open input keyboard
-	input, err := os.Open(inputPath)
+	cfg, err := load()
 	if err != nil {
-		log.Fatalf("failed to open input device %s: %v", inputPath, err)
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	tray.Enabled = cfg.Tray
+	if isFlagSet("openai.key") {
+		slog.Warn("the -openai.key flag is visible to other users, use -openai.key-file instead")
 	}
-	defer input.Close()
 	// create output keyboard
-	output, err := uinput.Create("whisperd")
+	setup := uinput.Setup{ID: uinput.DefaultID}
+	setup.ID.Vendor = cfg.Uinput.Vendor
+	setup.ID.Product = cfg.Uinput.Product
+	if err := setup.SetName(cfg.Uinput.Name); err != nil {
+		log.Fatalf("invalid uinput name: %v", err)
+	}
+	keyboard, err := uinput.CreateDevice(setup)
 	if err != nil {
 		log.Fatalf("failed to create uinput device: %v", err)
 	}
-	defer output.Close()
-	defer uinput.Destroy(output)
+	defer keyboard.Close()
+	defer uinput.Destroy(keyboard)
+	logger := slog.Default()
+	spacingState := &postproc.Spacing{}
+	dcfg, err := build(cfg, keyboard, spacingState, logger)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
 	d := &daemon.Daemon{
-		Log:     slog.Default(),
-		Input:   input,
-		Output:  output,
-		Client:  openai.Client{APIKey: openaiKey, BaseURL: openaiBaseURL},
-		KeyCode: uint16(keyCode),
-		Dump:    dump,
+		Config: dcfg,
+		Log:    logger,
 	}
-	ctx := context.Background()
+	var rulesFile atomic.Value
+	rulesFile.Store(cfg.RulesFile)
+	// reload re-reads the configuration without recreating the uinput device
+	reload := func() error {
+		cfg, err := load()
+		if err != nil {
+			return err
+		}
+		dcfg, err := build(cfg, keyboard, spacingState, logger)
+		if err != nil {
+			return err
+		}
+		if err := d.Reload(ctx, dcfg); err != nil {
+			return err
+		}
+		rulesFile.Store(cfg.RulesFile)
+		tray.SetProfiles(trayProfiles(cfg))
+		return nil
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reload(); err != nil {
+				logger.Error("failed to reload configuration", "error", err)
+			}
+		}
+	}()
+	// the rules file of the current configuration is watched along with the configuration file
+	go config.Watch(ctx, 2*time.Second, func() []string {
+		return []string{configPath, rulesFile.Load().(string)}
+	}, func() {
+		if err := reload(); err != nil {
+			logger.Error("failed to reload configuration", "error", err)
+		}
+	})
+	if cfg.Socket != "" {
+		go func() {
+			if err := control.Serve(ctx, cfg.Socket, func(req control.Request) control.Response {
+				switch req.Command {
+				case "status":
+					return control.Response{OK: true, Status: d.Status().String()}
+				case "last":
+					last, ok := d.History.Last()
+					if !ok {
+						return control.Response{Error: "no transcript"}
+					}
+					return control.Response{OK: true, Text: last.Text}
+				case "reload":
+					if err := reload(); err != nil {
+						return control.Response{Error: err.Error()}
+					}
+					return control.Response{OK: true}
+				default:
+					if err := d.Send(ctx, daemon.Command(req.Command), req.Profile); err != nil {
+						return control.Response{Error: err.Error()}
+					}
+					return control.Response{OK: true}
+				}
+			}); err != nil {
+				log.Fatalf("control socket: %v", err)
+			}
+		}()
+	}
+	if cfg.DBus {
+		go func() {
+			if err := dbusapi.Serve(ctx, d); err != nil {
+				logger.Warn("failed to export d-bus service", "error", err)
+			}
+		}()
+	}
+	if cfg.Notify.Enabled || cfg.Notify.Transcripts {
+		n := &notify.Notifier{
+			Interval:    cfg.Notify.Interval,
+			Transcripts: cfg.Notify.Transcripts,
+		}
+		if err := n.Connect(); err != nil {
+			logger.Warn("failed to connect to the notification service", "error", err)
+		} else {
+			d.Listen(n.HandleEvent)
+		}
+	}
+	tray.SetProfiles(trayProfiles(cfg))
+	if sources, err := traySources(ctx, dcfg.Audio); err != nil {
+		logger.Warn("failed to list audio sources", "error", err)
+	} else {
+		tray.SetSources(sources)
+	}
+	syncTray(d)
+	go handleTray(ctx, d, configPath, logger)
 	tray.Run(func() {
 		go func() {
 			if err := d.Run(ctx); err != nil {
@@ -64,3 +367,14 @@ func main() {
 		}()
 	})
 }
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	var set bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
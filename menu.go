@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/icholy/whisperd/internal/audio"
+	"github.com/icholy/whisperd/internal/config"
+	"github.com/icholy/whisperd/internal/daemon"
+	"github.com/icholy/whisperd/internal/output"
+	"github.com/icholy/whisperd/internal/tray"
+)
+
+// trayProfiles returns the profiles which can be selected in the tray menu.
+func trayProfiles(cfg *config.Config) []tray.Choice {
+	var choices []tray.Choice
+	for i, p := range cfg.Profiles {
+		switch {
+		case p.Name != "":
+			choices = append(choices, tray.Choice{Value: p.Name, Label: p.Name})
+		case i == 0:
+			// an empty name selects the first profile
+			choices = append(choices, tray.Choice{Value: "", Label: "default"})
+		}
+	}
+	return choices
+}
+
+// traySources returns the audio sources which can be selected in the tray menu.
+func traySources(ctx context.Context, backend audio.Backend) ([]tray.Choice, error) {
+	sources, err := audio.ListSources(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+	choices := []tray.Choice{{Value: "", Label: "Default"}}
+	for _, s := range sources {
+		choices = append(choices, tray.Choice{Value: s.Name, Label: s.Description})
+	}
+	return choices, nil
+}
+
+// syncTray keeps the tray menu up to date with the daemon.
+func syncTray(d *daemon.Daemon) {
+	d.Listen(func(e daemon.Event) {
+		switch e.Kind {
+		case daemon.SettingsChanged:
+			s := d.Settings()
+			tray.SetPaused(s.Paused)
+			tray.SetHandsFree(s.HandsFree)
+			tray.SetSelected(s.Profile, s.Source)
+		case daemon.Transcribed:
+			var texts []string
+			for _, entry := range d.History.Entries() {
+				texts = append(texts, entry.Text)
+			}
+			tray.SetHistory(texts)
+		}
+	})
+}
+
+// handleTray performs the actions selected in the tray menu.
+func handleTray(ctx context.Context, d *daemon.Daemon, configPath string, log *slog.Logger) {
+	for a := range tray.Actions() {
+		var err error
+		switch a.Kind {
+		case tray.RepeatLast:
+			err = d.Send(ctx, daemon.Repeat, "")
+		case tray.Pause:
+			err = d.Send(ctx, daemon.Pause, "")
+		case tray.Resume:
+			err = d.Send(ctx, daemon.Resume, "")
+		case tray.HandsFree:
+			err = d.Send(ctx, daemon.HandsFree, "")
+		case tray.SelectProfile:
+			err = d.Send(ctx, daemon.Select, a.Value)
+		case tray.SelectSource:
+			err = d.Send(ctx, daemon.Source, a.Value)
+		case tray.CopyLast:
+			if last, ok := d.History.Last(); ok {
+				err = output.Copy(ctx, last.Text)
+			}
+		case tray.Copy:
+			err = output.Copy(ctx, a.Value)
+		case tray.OpenConfig:
+			err = openConfig(ctx, configPath)
+		case tray.Quit:
+			log.Info("quitting")
+			tray.Stop()
+		}
+		if err != nil {
+			log.Error("menu action failed", "error", err)
+		}
+	}
+}
+
+// openConfig opens the configuration file with the default application,
+// creating an empty file if it doesn't exist.
+func openConfig(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			return err
+		}
+	}
+	return exec.CommandContext(ctx, "xdg-open", path).Run()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/icholy/whisperd/internal/audio"
+)
+
+// listSources implements the "whisperd list-sources" subcommand which prints
+// the names that can be used with -audio.device and profile sources.
+func listSources(args []string) {
+	fs := flag.NewFlagSet("list-sources", flag.ExitOnError)
+	backendName := fs.String("audio.backend", "auto", "audio backend: auto, pipewire, pulseaudio or alsa")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: whisperd list-sources [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	backend, err := audio.New(*backendName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "whisperd: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sources, err := audio.ListSources(ctx, backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "whisperd: %v\n", err)
+		os.Exit(1)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION")
+	for _, s := range sources {
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Description)
+	}
+	w.Flush()
+}